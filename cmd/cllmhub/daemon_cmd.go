@@ -3,19 +3,25 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/cllmhub/cllmhub-cli/internal/daemon"
 	"github.com/spf13/cobra"
 )
 
-var daemonWatch bool
+var (
+	daemonWatch         bool
+	daemonWatchInterval time.Duration
+	daemonLogFormat     string
+	daemonHealthAddr    string
+)
 
 var daemonCmd = &cobra.Command{
 	Use:    "__daemon",
 	Hidden: true,
 	Short:  "Run the daemon process (internal use only)",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		d := daemon.New(daemon.Options{Watch: daemonWatch})
+		d := daemon.New(daemon.Options{Watch: daemonWatch, WatchInterval: daemonWatchInterval, LogFormat: daemonLogFormat, HealthAddr: daemonHealthAddr})
 		if err := d.Run(); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			return err
@@ -26,4 +32,7 @@ var daemonCmd = &cobra.Command{
 
 func init() {
 	daemonCmd.Flags().BoolVarP(&daemonWatch, "watch", "w", false, "Proactively watch backend health and unpublish unreachable models")
+	daemonCmd.Flags().DurationVar(&daemonWatchInterval, "watch-interval", 0, "Backend health check interval with --watch (default 30s)")
+	daemonCmd.Flags().StringVar(&daemonLogFormat, "log-format", daemon.LogFormatJSON, "Daemon log format: json or text")
+	daemonCmd.Flags().StringVar(&daemonHealthAddr, "health-addr", "", "Serve /healthz and /readyz probes on this address, e.g. :8081")
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/cllmhub/cllmhub-cli/internal/auth"
 	"github.com/cllmhub/cllmhub-cli/internal/backend"
 	"github.com/cllmhub/cllmhub-cli/internal/daemon"
+	"github.com/cllmhub/cllmhub-cli/internal/hub"
 	"github.com/cllmhub/cllmhub-cli/internal/tui"
 	"github.com/spf13/cobra"
 )
@@ -45,6 +46,10 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	if loginUseLocalhost {
 		hubURL = "http://localhost:8080"
 	}
+	hubURL, err := hub.NormalizeURL(hubURL)
+	if err != nil {
+		return err
+	}
 
 	// Capture existing credentials so we can revoke them after a successful login.
 	oldCreds, oldCredsErr := auth.LoadCredentials()
@@ -83,7 +88,7 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	fmt.Println("Initiating device authorization...")
+	infof("Initiating device authorization...\n")
 
 	dar, err := auth.StartDeviceAuth(ctx, hubURL)
 	if err != nil {
@@ -107,7 +112,7 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	fmt.Println("Waiting for authorization...")
+	infof("Waiting for authorization...\n")
 
 	tr, err := auth.PollForToken(ctx, hubURL, dar)
 	if err != nil {
@@ -145,12 +150,10 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		idx := tui.Select("Select a model to publish (or Esc to skip):", labels)
 		if idx >= 0 {
 			selected := entries[idx]
-			return publishViaDaemon(selected.name, selected.backend, "", "", "", 0)
+			return publishViaDaemon(daemon.PublishModelSpec{Name: selected.name, BackendType: selected.backend})
 		}
 	} else {
-		fmt.Println()
-		fmt.Println("To publish a model:")
-		fmt.Println("  cllmhub publish -m <model-name>")
+		infof("\nTo publish a model:\n  cllmhub publish -m <model-name>\n")
 	}
 
 	return nil
@@ -8,11 +8,26 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Build metadata, injected at build time via -ldflags -X.
 var (
-	Version    = "dev"
-	verChecker *versioncheck.Checker
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
 )
 
+var verChecker *versioncheck.Checker
+
+// quiet suppresses informational output (progress and banners); results,
+// warnings, and errors are still printed.
+var quiet bool
+
+// infof prints an informational line unless --quiet is set.
+func infof(format string, args ...any) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "cllmhub",
 	Short: "cLLMHub CLI - Publish local LLMs to the cLLMHub network",
@@ -29,12 +44,12 @@ Quick start:
 
 Supported backends: Ollama, vLLM, LM Studio, llama.cpp, MLX`,
 	SilenceUsage: true,
-	Version:      Version,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runStatus(cmd, args)
 	},
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		if cmd.Name() != "update" {
+		removeOldBinary()
+		if cmd.Name() != "update" && cmd.Name() != "version" && !quiet {
 			verChecker = versioncheck.New(Version)
 		}
 	},
@@ -51,10 +66,16 @@ Supported backends: Ollama, vLLM, LM Studio, llama.cpp, MLX`,
 
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.SetVersionTemplate("{{.Version}}")
+	rootCmd.Version = versionString()
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Suppress informational output; print only results and errors")
 
 	rootCmd.AddCommand(publishCmd)
 	rootCmd.AddCommand(unpublishCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
 	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(loginCmd)
 	rootCmd.AddCommand(logoutCmd)
 	rootCmd.AddCommand(whoamiCmd)
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cllmhub/cllmhub-cli/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause [model...]",
+	Short: "Stop accepting new requests without unpublishing",
+	Long: `Pause published models for maintenance. A paused model stays connected
+and finishes its in-flight requests, but reports itself as paused so the
+gateway routes new requests elsewhere. With no arguments, every published
+model is paused.
+
+On Linux and macOS, sending SIGUSR1 to the daemon toggles pause for every
+model.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSetPaused(args, true)
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [model...]",
+	Short: "Resume accepting requests for paused models",
+	Long:  `Resume paused models. With no arguments, every published model is resumed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSetPaused(args, false)
+	},
+}
+
+func runSetPaused(args []string, paused bool) error {
+	running, _ := daemon.IsRunning()
+	if !running {
+		return fmt.Errorf("daemon is not running — no models are published")
+	}
+
+	client, err := daemon.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+
+	action, state := client.Resume, "resumed"
+	if paused {
+		action, state = client.Pause, "paused"
+	}
+	resp, err := action(args)
+	if err != nil {
+		return err
+	}
+	if len(resp.Results) == 0 {
+		return fmt.Errorf("no models are currently published")
+	}
+
+	var failures int
+	for _, r := range resp.Results {
+		if r.Success {
+			fmt.Printf("%-20s %s\n", r.Model, state)
+		} else {
+			fmt.Printf("%-20s error: %s\n", r.Model, r.Error)
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d models failed to be %s", failures, len(resp.Results), state)
+	}
+	return nil
+}
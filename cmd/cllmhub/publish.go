@@ -1,23 +1,57 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"regexp"
+	"strings"
+	"time"
 
+	"github.com/cllmhub/cllmhub-cli/internal/backend"
 	"github.com/cllmhub/cllmhub-cli/internal/daemon"
+	"github.com/cllmhub/cllmhub-cli/internal/provider"
 	"github.com/cllmhub/cllmhub-cli/internal/tui"
 	"github.com/spf13/cobra"
 )
 
 var (
-	publishModel         string
-	publishBackend       string
-	publishBackendURL    string
-	publishBackendAPIKey string
-	publishDescription   string
-	publishMaxConcurrent int
+	publishModel          string
+	publishBackend        string
+	publishBackendURL     string
+	publishBackendAPIKey  string
+	publishDescription    string
+	publishMaxConcurrent  int
+	publishOllamaContext  bool
+	publishMaxTokensLimit int
+	publishKeepAlive      string
+
+	publishRequestTimeout time.Duration
+
+	publishHealthRetries       int
+	publishHealthRetryInterval time.Duration
+
+	publishWaitForBackend bool
+	publishWaitTimeout    time.Duration
+
+	publishLlamaCppChat bool
+
+	publishMaxQueue int
+
+	publishProviderID string
+
+	publishCompletionsPath string
+	publishChatPath        string
+	publishInfillPath      string
+
+	publishCoalesceMs int
+
+	publishBackendModel string
 )
 
+// backendAPIKeyEnv is read when --api-key is not given.
+const backendAPIKeyEnv = "CLLMHUB_BACKEND_API_KEY"
+
 var publishCmd = &cobra.Command{
 	Use:   "publish",
 	Short: "Publish a local LLM to the cLLMHub network",
@@ -29,7 +63,8 @@ bridge services. The terminal is never blocked.
 Use -m/-b flags to publish models served by an external backend (Ollama, vLLM, etc.).
 If no flags are provided, the CLI will discover running backends and let you pick a model.
 
-Supported backends: ollama, llama.cpp, vllm, lmstudio, mlx`,
+Supported backends: ollama, llama.cpp, vllm, lmstudio, mlx, and mock (a
+canned-response backend for testing the publish flow)`,
 	Example: `  # Publish a model from Ollama
   cllmhub publish -m "llama3-70b" -b ollama
 
@@ -39,6 +74,15 @@ Supported backends: ollama, llama.cpp, vllm, lmstudio, mlx`,
   # Publish with authentication
   cllmhub publish -m "my-model" -b mlx --api-key sk-xxx
 
+  # Reference the key from the environment instead of the command line
+  cllmhub publish -m "my-model" -b vllm --api-key '${VLLM_API_KEY}'
+
+  # Advertise a friendly name for the backend's own model name
+  cllmhub publish -m "llama3-70b" -b vllm --backend-model meta-llama-3-70b-instruct
+
+  # Wait for a slow-loading model before publishing
+  cllmhub publish -m "llama3-70b" -b vllm --wait-for-backend --wait-timeout 15m
+
   # Interactive selection from detected backends
   cllmhub publish`,
 	RunE: runPublish,
@@ -46,20 +90,67 @@ Supported backends: ollama, llama.cpp, vllm, lmstudio, mlx`,
 
 func init() {
 	publishCmd.Flags().StringVarP(&publishModel, "model", "m", "", "Model name to publish")
-	publishCmd.Flags().StringVarP(&publishBackend, "backend", "b", "ollama", "Backend type: ollama, llama.cpp, vllm, lmstudio, mlx")
-	publishCmd.Flags().StringVar(&publishBackendURL, "backend-url", "", "Backend endpoint URL (overrides default for the backend type)")
-	publishCmd.Flags().StringVar(&publishBackendAPIKey, "api-key", "", "API key for the backend server")
+	publishCmd.Flags().StringVarP(&publishBackend, "backend", "b", "ollama", "Backend type: ollama, llama.cpp, vllm, lmstudio, mlx, mock")
+	publishCmd.Flags().StringVar(&publishBackendModel, "backend-model", "", "Model name the backend serves, when it differs from the published name (default: the --model value)")
+	publishCmd.Flags().StringVar(&publishBackendURL, "backend-url", "", "Backend endpoint URL (overrides default for the backend type); ${VAR} is expanded")
+	publishCmd.Flags().StringVar(&publishBackendAPIKey, "api-key", "", "API key for the backend server; ${VAR} is expanded (default: $"+backendAPIKeyEnv+")")
 	publishCmd.Flags().StringVarP(&publishDescription, "description", "d", "", "Model description")
+	publishCmd.Flags().IntVar(&publishMaxTokensLimit, "max-tokens-limit", 0, "Clamp requested max_tokens to this value; also applied when none is requested (0 = no limit)")
+	publishCmd.Flags().StringVar(&publishKeepAlive, "keep-alive", "", "How long Ollama keeps the model loaded between requests: seconds (-1 = forever) or a duration like 30m (ollama only)")
+	publishCmd.Flags().BoolVar(&publishLlamaCppChat, "llamacpp-chat", false, "Send prompts to llama.cpp's /v1/chat/completions so the chat template is applied (llama.cpp only)")
+	publishCmd.Flags().BoolVar(&publishOllamaContext, "ollama-context", false, "Reuse Ollama's context between turns of the same session (ollama only)")
+	publishCmd.Flags().DurationVar(&publishRequestTimeout, "request-timeout", 0, "Fail a request if the backend takes longer than this, e.g. 5m (0 = no timeout)")
+	publishCmd.Flags().IntVar(&publishHealthRetries, "health-retries", 0, "Retry the startup backend health check this many times before giving up")
+	publishCmd.Flags().DurationVar(&publishHealthRetryInterval, "health-retry-interval", 2*time.Second, "Delay before the first health check retry; doubles after each retry (max 30s)")
+	publishCmd.Flags().BoolVar(&publishWaitForBackend, "wait-for-backend", false, "Wait until the backend is healthy (e.g. the model has loaded) before publishing")
+	publishCmd.Flags().DurationVar(&publishWaitTimeout, "wait-timeout", 10*time.Minute, "Give up waiting for the backend after this long (with --wait-for-backend)")
 	publishCmd.Flags().IntVar(&publishMaxConcurrent, "max-concurrent", 0, "Max concurrent slots ceiling (default: auto-detect, starting at 1, max 5)")
+	publishCmd.Flags().StringVar(&publishCompletionsPath, "completions-path", "", "Backend prompt-completion path, for servers behind a reverse proxy (default depends on the backend, e.g. /api/generate)")
+	publishCmd.Flags().StringVar(&publishChatPath, "chat-path", "", "Backend chat path, for servers behind a reverse proxy (default depends on the backend, e.g. /api/chat)")
+	publishCmd.Flags().StringVar(&publishInfillPath, "infill-path", "", "Backend fill-in-the-middle path, for servers behind a reverse proxy (default /infill; llama.cpp only)")
+	publishCmd.Flags().StringVar(&publishProviderID, "provider-id", "", "Provider ID to register under (default: a stable ID for this machine and model)")
+	publishCmd.Flags().IntVar(&publishCoalesceMs, "coalesce-ms", 0, "Combine streamed tokens produced within this many milliseconds into one message to the gateway (0 = send each token)")
+	publishCmd.Flags().IntVar(&publishMaxQueue, "max-queue", 0, "Reject requests with \"provider busy\" when this many are already waiting for a slot (0 = no limit)")
 }
 
 func runPublish(cmd *cobra.Command, args []string) error {
+	if !cmd.Flags().Changed("api-key") {
+		publishBackendAPIKey = os.Getenv(backendAPIKeyEnv)
+	}
+
+	var err error
+	if publishBackendURL, err = expandEnvRefs("--backend-url", publishBackendURL); err != nil {
+		return err
+	}
+	if publishBackendAPIKey, err = expandEnvRefs("--api-key", publishBackendAPIKey); err != nil {
+		return err
+	}
+
 	// If -m flag provided, publish that model with the specified backend
 	if cmd.Flags().Changed("model") || cmd.Flags().Changed("backend") {
 		if publishModel == "" {
 			return fmt.Errorf("model name is required: use -m <model>")
 		}
-		return publishViaDaemon(publishModel, publishBackend, publishBackendURL, publishBackendAPIKey, publishDescription, publishMaxConcurrent)
+		spec := publishSpec(publishModel, publishBackend)
+		if publishWaitForBackend {
+			b, err := backend.New(backend.Config{
+				Type:   spec.BackendType,
+				URL:    spec.BackendURL,
+				Model:  spec.ServedModel(),
+				APIKey: spec.BackendAPIKey,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create backend: %w", err)
+			}
+			if err := waitForBackend(b, publishWaitTimeout, backendPollInterval); err != nil {
+				return err
+			}
+		}
+		return publishViaDaemon(spec)
+	}
+
+	if publishBackendModel != "" {
+		return fmt.Errorf("--backend-model requires -m: interactively selected models are published under their backend name")
 	}
 
 	// Interactive TUI selection from detected backends
@@ -79,7 +170,105 @@ func runPublish(cmd *cobra.Command, args []string) error {
 	}
 	selected := available[idx]
 
-	return publishViaDaemon(selected.name, selected.source, publishBackendURL, publishBackendAPIKey, publishDescription, publishMaxConcurrent)
+	return publishViaDaemon(publishSpec(selected.name, selected.source))
+}
+
+// How often --wait-for-backend checks backend health, and how often it
+// reports that it is still waiting.
+const (
+	backendPollInterval     = 2 * time.Second
+	backendProgressInterval = 15 * time.Second
+)
+
+// waitForBackend polls b's health check until it succeeds or timeout passes,
+// printing progress. Models can take minutes to load into VRAM; publishing
+// before they are ready would fail the daemon's own health check.
+func waitForBackend(b backend.Backend, timeout, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	lastReport := start
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	infof("Waiting for %s backend at %s...\n", b.Name(), b.URL())
+	for {
+		checkCtx, checkCancel := context.WithTimeout(ctx, 10*time.Second)
+		err := b.Health(checkCtx)
+		checkCancel()
+		if err == nil {
+			infof("✓ Backend ready after %s\n", time.Since(start).Round(time.Second))
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("backend not ready after %s: %w", timeout, err)
+		case <-ticker.C:
+		}
+		if time.Since(lastReport) >= backendProgressInterval {
+			infof("  still waiting (%s): %v\n", time.Since(start).Round(time.Second), err)
+			lastReport = time.Now()
+		}
+	}
+}
+
+// publishSpec builds the daemon publish spec for model from the publish flags.
+func publishSpec(model, backendType string) daemon.PublishModelSpec {
+	return daemon.PublishModelSpec{
+		Name:           model,
+		BackendType:    backendType,
+		BackendURL:     publishBackendURL,
+		BackendAPIKey:  publishBackendAPIKey,
+		Description:    publishDescription,
+		MaxConcurrent:  publishMaxConcurrent,
+		MaxTokensLimit: publishMaxTokensLimit,
+		OllamaContext:  publishOllamaContext,
+
+		OllamaKeepAlive: publishKeepAlive,
+		RequestTimeout:  publishRequestTimeout,
+
+		HealthRetries:       publishHealthRetries,
+		HealthRetryInterval: publishHealthRetryInterval,
+
+		LlamaCppChat: publishLlamaCppChat,
+
+		MaxQueue: publishMaxQueue,
+
+		ProviderID: publishProviderID,
+
+		CompletionsPath: publishCompletionsPath,
+		ChatPath:        publishChatPath,
+		InfillPath:      publishInfillPath,
+
+		StreamCoalesce: time.Duration(publishCoalesceMs) * time.Millisecond,
+
+		BackendModel: publishBackendModel,
+	}
+}
+
+// envRefPattern matches ${VAR} references. Bare $VAR is deliberately left
+// alone so keys containing a literal '$' pass through unchanged.
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvRefs replaces ${VAR} references in a flag value with the
+// variable's value, so secrets can be kept out of scripts and shell history.
+// Referencing an unset variable is an error rather than a silent empty string.
+func expandEnvRefs(flag, value string) (string, error) {
+	var missing []string
+	expanded := envRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%s references unset environment variable(s): %s", flag, strings.Join(missing, ", "))
+	}
+	return expanded, nil
 }
 
 // publishableModel represents a model that can be published, from any source.
@@ -112,7 +301,7 @@ func listAllPublishable() []publishableModel {
 func ensureDaemon() error {
 	running, _ := daemon.IsRunning()
 	if !running {
-		fmt.Println("Starting daemon...")
+		infof("Starting daemon...\n")
 		if err := runStart(nil, nil); err != nil {
 			return fmt.Errorf("failed to start daemon: %w", err)
 		}
@@ -121,12 +310,38 @@ func ensureDaemon() error {
 }
 
 // publishViaDaemon publishes a model served by an external backend through the daemon.
-func publishViaDaemon(model, backendType, backendURL, apiKey, description string, maxConcurrent int) error {
-	if !regexp.MustCompile(`^[a-zA-Z0-9._:/-]+$`).MatchString(model) {
-		return fmt.Errorf("invalid model name %q: only alphanumerics, dots, underscores, colons, slashes, and hyphens are allowed", model)
+func publishViaDaemon(spec daemon.PublishModelSpec) error {
+	if !regexp.MustCompile(`^[a-zA-Z0-9._:/-]+$`).MatchString(spec.Name) {
+		return fmt.Errorf("invalid model name %q: only alphanumerics, dots, underscores, colons, slashes, and hyphens are allowed", spec.Name)
+	}
+	if len(spec.Description) > 500 {
+		return fmt.Errorf("description too long (%d chars): maximum is 500", len(spec.Description))
+	}
+	if spec.MaxTokensLimit < 0 {
+		return fmt.Errorf("--max-tokens-limit must not be negative")
+	}
+	if spec.RequestTimeout < 0 {
+		return fmt.Errorf("--request-timeout must not be negative")
+	}
+	if spec.HealthRetries < 0 {
+		return fmt.Errorf("--health-retries must not be negative")
 	}
-	if len(description) > 500 {
-		return fmt.Errorf("description too long (%d chars): maximum is 500", len(description))
+	if spec.StreamCoalesce < 0 {
+		return fmt.Errorf("--coalesce-ms must not be negative")
+	}
+	if spec.MaxQueue < 0 {
+		return fmt.Errorf("--max-queue must not be negative")
+	}
+	if spec.ProviderID != "" {
+		if err := provider.ValidateProviderID(spec.ProviderID); err != nil {
+			return fmt.Errorf("--provider-id: %w", err)
+		}
+	}
+	if spec.OllamaContext && spec.BackendType != "ollama" {
+		return fmt.Errorf("--ollama-context is only supported with the ollama backend, not %q", spec.BackendType)
+	}
+	if spec.LlamaCppChat && spec.BackendType != "llamacpp" && spec.BackendType != "llama.cpp" {
+		return fmt.Errorf("--llamacpp-chat is only supported with the llama.cpp backend, not %q", spec.BackendType)
 	}
 
 	if err := ensureDaemon(); err != nil {
@@ -138,16 +353,7 @@ func publishViaDaemon(model, backendType, backendURL, apiKey, description string
 		return fmt.Errorf("failed to connect to daemon: %w", err)
 	}
 
-	spec := daemon.PublishModelSpec{
-		Name:          model,
-		BackendType:   backendType,
-		BackendURL:    backendURL,
-		BackendAPIKey: apiKey,
-		Description:   description,
-		MaxConcurrent: maxConcurrent,
-	}
-
-	fmt.Printf("Publishing %s (backend: %s)...\n", model, backendType)
+	infof("Publishing %s (backend: %s)...\n", spec.Name, spec.BackendType)
 	return printPublishResults(client.Publish([]daemon.PublishModelSpec{spec}))
 }
 
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cllmhub/cllmhub-cli/internal/backend"
+)
+
+func TestExpandEnvRefs(t *testing.T) {
+	t.Setenv("CLLMHUB_TEST_KEY", "sk-secret")
+	t.Setenv("CLLMHUB_TEST_PORT", "9000")
+
+	cases := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"sk-literal", "sk-literal"},
+		{"${CLLMHUB_TEST_KEY}", "sk-secret"},
+		{"http://localhost:${CLLMHUB_TEST_PORT}/v1", "http://localhost:9000/v1"},
+		{"sk-$dollar", "sk-$dollar"}, // bare $ is not a reference
+	}
+	for _, tc := range cases {
+		got, err := expandEnvRefs("--api-key", tc.in)
+		if err != nil {
+			t.Errorf("expandEnvRefs(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("expandEnvRefs(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestExpandEnvRefs_Unset(t *testing.T) {
+	_, err := expandEnvRefs("--api-key", "${CLLMHUB_TEST_DEFINITELY_UNSET}")
+	if err == nil {
+		t.Fatal("expected error for unset variable")
+	}
+	if !strings.Contains(err.Error(), "CLLMHUB_TEST_DEFINITELY_UNSET") {
+		t.Errorf("error should name the variable, got %v", err)
+	}
+}
+
+func TestWaitForBackend(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Still loading for the first two checks.
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"models":[{"name":"llama3:latest"}]}`))
+	}))
+	defer srv.Close()
+
+	b, err := backend.NewOllama(backend.Config{URL: srv.URL, Model: "llama3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForBackend(b, 5*time.Second, time.Millisecond); err != nil {
+		t.Fatalf("waitForBackend: %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("health checks = %d, want 3", got)
+	}
+}
+
+func TestWaitForBackend_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	b, err := backend.NewOllama(backend.Config{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForBackend(b, 20*time.Millisecond, time.Millisecond); err == nil {
+		t.Fatal("expected an error from a backend that never becomes ready")
+	}
+}
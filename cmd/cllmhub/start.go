@@ -10,7 +10,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var startWatch bool
+var (
+	startWatch         bool
+	startWatchInterval time.Duration
+	startLogFormat     string
+	startHealthAddr    string
+)
 
 var startCmd = &cobra.Command{
 	Use:   "start",
@@ -21,15 +26,23 @@ The daemon manages model publishing bridges that connect external backends
 (Ollama, vLLM, LM Studio, MLX, llama.cpp) to the cLLMHub network.
 
 By default, models are unpublished only when a client request fails to reach
-the backend. Use --watch to proactively monitor backend health and unpublish
-unreachable models even when no requests are flowing.`,
+the backend. Use --watch to proactively monitor backend health even when no
+requests are flowing. A model whose backend fails a check is marked degraded,
+so the gateway stops routing to it, and returns to online once the backend
+recovers. It is unpublished only if the backend stays down for several checks.`,
 	Example: `  cllmhub start
-  cllmhub start --watch`,
+  cllmhub start --watch
+  cllmhub start --watch --watch-interval 10s
+  cllmhub start --log-format text
+  cllmhub start --health-addr :8081`,
 	RunE: runStart,
 }
 
 func init() {
 	startCmd.Flags().BoolVarP(&startWatch, "watch", "w", false, "Proactively watch backend health and unpublish unreachable models")
+	startCmd.Flags().DurationVar(&startWatchInterval, "watch-interval", 0, "Backend health check interval with --watch (default 30s)")
+	startCmd.Flags().StringVar(&startLogFormat, "log-format", daemon.LogFormatJSON, "Daemon log format: json or text")
+	startCmd.Flags().StringVar(&startHealthAddr, "health-addr", "", "Serve /healthz and /readyz probes on this address, e.g. :8081")
 }
 
 func runStart(cmd *cobra.Command, args []string) error {
@@ -39,6 +52,10 @@ func runStart(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if err := daemon.CheckLogFormat(startLogFormat); err != nil {
+		return err
+	}
+
 	// Open log file for daemon stdout/stderr
 	logDir, err := daemon.LogDir()
 	if err != nil {
@@ -65,6 +82,13 @@ func runStart(cmd *cobra.Command, args []string) error {
 	if startWatch {
 		daemonArgs = append(daemonArgs, "--watch")
 	}
+	if startWatchInterval > 0 {
+		daemonArgs = append(daemonArgs, "--watch-interval", startWatchInterval.String())
+	}
+	daemonArgs = append(daemonArgs, "--log-format", startLogFormat)
+	if startHealthAddr != "" {
+		daemonArgs = append(daemonArgs, "--health-addr", startHealthAddr)
+	}
 	daemonProcess := exec.Command(executable, daemonArgs...)
 	daemonProcess.Stdout = logFile
 	daemonProcess.Stderr = logFile
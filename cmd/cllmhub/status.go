@@ -40,14 +40,17 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Println("\nPublished models:")
 		for _, m := range status.Models {
-			concurrent := ""
+			details := ""
 			if m.MaxConcurrent > 0 {
-				concurrent = fmt.Sprintf(", slots:%d", m.MaxConcurrent)
+				details = fmt.Sprintf(", slots:%d", m.MaxConcurrent)
+			}
+			if m.HeartbeatFailures > 0 {
+				details += fmt.Sprintf(", heartbeat failures:%d", m.HeartbeatFailures)
 			}
 			if m.ProviderID != "" {
-				fmt.Printf("  %-20s %s (provider:%s, %s%s)\n", m.Name, m.State, m.ProviderID, m.Backend, concurrent)
+				fmt.Printf("  %-20s %s (provider:%s, %s%s)\n", m.Name, m.State, m.ProviderID, m.Backend, details)
 			} else {
-				fmt.Printf("  %-20s %s (%s%s)\n", m.Name, m.State, m.Backend, concurrent)
+				fmt.Printf("  %-20s %s (%s%s)\n", m.Name, m.State, m.Backend, details)
 			}
 		}
 	}
@@ -27,7 +27,7 @@ func runStop(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to connect to daemon: %w", err)
 	}
 
-	fmt.Println("Stopping daemon...")
+	infof("Stopping daemon...\n")
 
 	if err := client.Stop(); err != nil {
 		return fmt.Errorf("failed to stop daemon: %w", err)
@@ -54,7 +54,7 @@ func runUnpublish(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, name := range args {
-		fmt.Printf("Unpublishing %s...\n", name)
+		infof("Unpublishing %s...\n", name)
 	}
 
 	resp, err := client.Unpublish(args)
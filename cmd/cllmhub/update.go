@@ -14,15 +14,31 @@ import (
 	"time"
 
 	"github.com/cllmhub/cllmhub-cli/internal/daemon"
+	"github.com/cllmhub/cllmhub-cli/internal/versioncheck"
 	"github.com/spf13/cobra"
 )
 
+var (
+	updateForce bool
+	updateCheck bool
+)
+
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update cllmhub to the latest version",
-	Long:  `Download and install the latest release binary from GitHub.`,
-	Example: `  cllmhub update`,
-	RunE:  runUpdate,
+	Long: `Download and install the latest release binary from GitHub.
+
+Nothing is downloaded if the installed version is already the latest
+release; use --force to reinstall anyway.`,
+	Example: `  cllmhub update
+  cllmhub update --check
+  cllmhub update --force`,
+	RunE: runUpdate,
+}
+
+func init() {
+	updateCmd.Flags().BoolVar(&updateForce, "force", false, "Reinstall even if already on the latest version")
+	updateCmd.Flags().BoolVar(&updateCheck, "check", false, "Only report whether an update is available")
 }
 
 const (
@@ -35,13 +51,27 @@ type githubRelease struct {
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
-	fmt.Println("Checking for updates...")
+	infof("Checking for updates...\n")
 
 	version, err := getLatestVersion()
 	if err != nil {
 		return fmt.Errorf("failed to get latest version: %w", err)
 	}
-	fmt.Printf("Latest version: %s\n", version)
+	fmt.Printf("Latest version: %s (current: %s)\n", version, Version)
+
+	available := versioncheck.IsNewer(version, Version)
+	if updateCheck {
+		if available {
+			fmt.Println("An update is available. Run \"cllmhub update\" to upgrade.")
+		} else {
+			fmt.Println("cllmhub is already up to date.")
+		}
+		return nil
+	}
+	if !available && !updateForce {
+		fmt.Println("cllmhub is already up to date.")
+		return nil
+	}
 
 	filename := fmt.Sprintf("%s-%s-%s", binaryName, runtime.GOOS, runtime.GOARCH)
 	if runtime.GOOS == "windows" {
@@ -49,7 +79,7 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	}
 
 	url := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, version, filename)
-	fmt.Printf("Downloading %s...\n", url)
+	infof("Downloading %s...\n", url)
 
 	resp, err := http.Get(url)
 	if err != nil {
@@ -80,7 +110,7 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	tmpFile.Close()
 
 	// Verify checksum
-	fmt.Println("Verifying checksum...")
+	infof("Verifying checksum...\n")
 	if err := verifyChecksum(version, filename, tmpFile.Name()); err != nil {
 		return fmt.Errorf("checksum verification failed: %w", err)
 	}
@@ -92,7 +122,7 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	// Stop daemon if running — it holds the old binary in memory
 	daemonWasRunning := false
 	if running, _ := daemon.IsRunning(); running {
-		fmt.Println("Stopping daemon before update...")
+		infof("Stopping daemon before update...\n")
 		if client, err := daemon.NewClient(); err == nil {
 			if err := client.Stop(); err != nil {
 				fmt.Printf("Warning: failed to stop daemon: %v\n", err)
@@ -109,8 +139,13 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Replace the current binary
-	if err := os.Rename(tmpFile.Name(), currentBin); err != nil {
+	// Replace the current binary. Windows refuses to overwrite a running
+	// executable, but does allow renaming it out of the way.
+	if runtime.GOOS == "windows" {
+		if err := swapBinary(tmpFile.Name(), currentBin); err != nil {
+			return fmt.Errorf("failed to replace binary: %w", err)
+		}
+	} else if err := os.Rename(tmpFile.Name(), currentBin); err != nil {
 		return fmt.Errorf("failed to replace binary: %w", err)
 	}
 
@@ -118,7 +153,7 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 
 	// Restart daemon if it was running before the update
 	if daemonWasRunning {
-		fmt.Println("Restarting daemon with new version...")
+		infof("Restarting daemon with new version...\n")
 		if err := runStart(nil, nil); err != nil {
 			fmt.Printf("Warning: failed to restart daemon: %v\n", err)
 			fmt.Println("Run 'cllmhub start' manually to restart.")
@@ -130,6 +165,39 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// swapBinary moves newBin into place at target by first renaming target to
+// target.old. If the new binary cannot be moved into place, the original is
+// restored so the user is never left without a working binary. The .old file
+// is removed on the next run by removeOldBinary.
+func swapBinary(newBin, target string) error {
+	old := target + ".old"
+	os.Remove(old) // leftover from a previous update
+
+	if err := os.Rename(target, old); err != nil {
+		return fmt.Errorf("cannot move current binary aside: %w", err)
+	}
+	if err := os.Rename(newBin, target); err != nil {
+		if restoreErr := os.Rename(old, target); restoreErr != nil {
+			return fmt.Errorf("%w (restore also failed, previous binary is at %s: %v)", err, old, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// removeOldBinary deletes the <binary>.old file left behind by a Windows
+// update. It is a no-op when there is nothing to clean up.
+func removeOldBinary() {
+	if runtime.GOOS != "windows" {
+		return
+	}
+	currentBin, err := os.Executable()
+	if err != nil {
+		return
+	}
+	os.Remove(currentBin + ".old")
+}
+
 func getLatestVersion() (string, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Get(fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo))
@@ -154,33 +222,13 @@ func getLatestVersion() (string, error) {
 	return release.TagName, nil
 }
 
-// verifyChecksum downloads checksums.txt from the release and verifies the
-// SHA-256 of the downloaded file matches the expected value.
+// verifyChecksum looks up the expected SHA-256 of filename in the release's
+// checksums.txt, falling back to the per-asset <filename>.sha256, and verifies
+// the downloaded file matches. Installation must be refused on any error.
 func verifyChecksum(version, filename, filepath string) error {
-	url := fmt.Sprintf("https://github.com/%s/releases/download/%s/checksums.txt", repo, version)
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(url)
+	expected, err := fetchExpectedChecksum(version, filename)
 	if err != nil {
-		return fmt.Errorf("failed to download checksums: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("checksums not available (HTTP %d); skipping verification", resp.StatusCode)
-	}
-
-	// Parse checksums.txt (format: "<hash>  <filename>" per line)
-	var expected string
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-		if len(parts) == 2 && parts[1] == filename {
-			expected = parts[0]
-			break
-		}
-	}
-	if expected == "" {
-		return fmt.Errorf("no checksum found for %s", filename)
+		return err
 	}
 
 	// Compute actual SHA-256
@@ -196,10 +244,59 @@ func verifyChecksum(version, filename, filepath string) error {
 	}
 	actual := hex.EncodeToString(h.Sum(nil))
 
-	if actual != expected {
+	if !strings.EqualFold(actual, expected) {
 		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
 	}
 
-	fmt.Println("Checksum verified.")
+	infof("Checksum verified.\n")
 	return nil
 }
+
+// fetchExpectedChecksum returns the published SHA-256 for filename.
+func fetchExpectedChecksum(version, filename string) (string, error) {
+	base := fmt.Sprintf("https://github.com/%s/releases/download/%s/", repo, version)
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	var errs []string
+	for _, asset := range []string{"checksums.txt", filename + ".sha256"} {
+		resp, err := client.Get(base + asset)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", asset, err))
+			continue
+		}
+		expected := ""
+		if resp.StatusCode == http.StatusOK {
+			expected = parseChecksum(resp.Body, filename)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			errs = append(errs, fmt.Sprintf("%s: HTTP %d", asset, resp.StatusCode))
+			continue
+		}
+		if expected == "" {
+			errs = append(errs, fmt.Sprintf("%s: no entry for %s", asset, filename))
+			continue
+		}
+		return expected, nil
+	}
+	return "", fmt.Errorf("no checksum available (%s)", strings.Join(errs, "; "))
+}
+
+// parseChecksum extracts the hash for filename from sha256sum-style output
+// ("<hash>  <filename>" per line). A single bare hash, as found in per-asset
+// .sha256 files, is also accepted.
+func parseChecksum(r io.Reader, filename string) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		switch {
+		case len(parts) == 1 && len(parts[0]) == sha256.Size*2:
+			return parts[0]
+		case len(parts) == 2 && strings.TrimPrefix(parts[1], "*") == filename:
+			// sha256sum marks binary-mode entries with a leading '*'.
+			return parts[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestParseChecksum_ChecksumsFile(t *testing.T) {
+	input := "0000000000000000000000000000000000000000000000000000000000000000  cllmhub-darwin-arm64\n" +
+		testHash + "  cllmhub-linux-amd64\n"
+	if got := parseChecksum(strings.NewReader(input), "cllmhub-linux-amd64"); got != testHash {
+		t.Errorf("parseChecksum = %q, want %q", got, testHash)
+	}
+}
+
+func TestParseChecksum_BinaryModeMarker(t *testing.T) {
+	input := testHash + " *cllmhub-windows-amd64.exe\n"
+	if got := parseChecksum(strings.NewReader(input), "cllmhub-windows-amd64.exe"); got != testHash {
+		t.Errorf("parseChecksum = %q, want %q", got, testHash)
+	}
+}
+
+func TestParseChecksum_BareHash(t *testing.T) {
+	if got := parseChecksum(strings.NewReader(testHash+"\n"), "cllmhub-linux-amd64"); got != testHash {
+		t.Errorf("parseChecksum = %q, want %q", got, testHash)
+	}
+}
+
+func TestParseChecksum_Missing(t *testing.T) {
+	input := testHash + "  cllmhub-darwin-arm64\n"
+	if got := parseChecksum(strings.NewReader(input), "cllmhub-linux-amd64"); got != "" {
+		t.Errorf("parseChecksum = %q, want empty", got)
+	}
+}
+
+func TestSwapBinary_ReplacesTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "cllmhub.exe")
+	newBin := filepath.Join(dir, "cllmhub-update")
+	os.WriteFile(target, []byte("old"), 0755)
+	os.WriteFile(newBin, []byte("new"), 0755)
+
+	if err := swapBinary(newBin, target); err != nil {
+		t.Fatalf("swapBinary: %v", err)
+	}
+
+	if data, _ := os.ReadFile(target); string(data) != "new" {
+		t.Errorf("target contents = %q, want %q", data, "new")
+	}
+	if data, _ := os.ReadFile(target + ".old"); string(data) != "old" {
+		t.Errorf(".old contents = %q, want %q", data, "old")
+	}
+}
+
+func TestSwapBinary_RestoresOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "cllmhub.exe")
+	os.WriteFile(target, []byte("old"), 0755)
+
+	// New binary does not exist, so moving it into place fails.
+	err := swapBinary(filepath.Join(dir, "missing"), target)
+	if err == nil {
+		t.Fatal("expected error when new binary is missing")
+	}
+
+	if data, _ := os.ReadFile(target); string(data) != "old" {
+		t.Errorf("target contents = %q after failed swap, want original %q", data, "old")
+	}
+	if _, err := os.Stat(target + ".old"); !os.IsNotExist(err) {
+		t.Error("expected .old to be moved back after failed swap")
+	}
+}
+
+func TestSwapBinary_OverwritesStaleOld(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "cllmhub.exe")
+	newBin := filepath.Join(dir, "cllmhub-update")
+	os.WriteFile(target, []byte("current"), 0755)
+	os.WriteFile(target+".old", []byte("stale"), 0755)
+	os.WriteFile(newBin, []byte("new"), 0755)
+
+	if err := swapBinary(newBin, target); err != nil {
+		t.Fatalf("swapBinary: %v", err)
+	}
+	if data, _ := os.ReadFile(target + ".old"); string(data) != "current" {
+		t.Errorf(".old contents = %q, want %q", data, "current")
+	}
+}
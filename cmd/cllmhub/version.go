@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show the cllmhub version and build information",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(versionString())
+	},
+}
+
+// versionString formats the build metadata injected via -ldflags.
+func versionString() string {
+	return fmt.Sprintf("cllmhub %s\n  commit:     %s\n  built:      %s\n  go version: %s\n  platform:   %s/%s\n",
+		Version, Commit, BuildDate, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -1,12 +1,15 @@
 package backend
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
+	"strings"
 	"syscall"
 )
 
@@ -37,24 +40,97 @@ type Backend interface {
 type Request struct {
 	Prompt      string
 	Messages    json.RawMessage // original chat messages with multimodal content parts
-	MaxTokens   int
+	MaxTokens   int             // 0 = backend default (omitted from the backend request)
 	Temperature float64
 	TopP        float64
+	Stop        []string // stop sequences; generation halts when any is produced
+	Seed        *int     // sampling seed; nil = backend default. Reproducible only if the backend honors it
+	SessionID   string   // groups the turns of one conversation; empty = stateless
+	LogProbs    *bool    // return token logprobs; only honored by vLLM, and only without streaming
+	TopLogProbs *int     // alternatives per token when LogProbs is set
+
+	// ResponseFormat is an OpenAI-style response_format, e.g.
+	// {"type":"json_object"} or {"type":"json_schema","json_schema":{...}}.
+	// Ollama and llama.cpp receive it translated to their own fields.
+	ResponseFormat json.RawMessage
+	// Grammar is a GBNF grammar constraining the output; only honored by
+	// llama.cpp.
+	Grammar string
+
+	// Prefix and Suffix are the text before and after the cursor for
+	// fill-in-the-middle (code completion); Prompt, if any, is extra text
+	// placed after Prefix. Supported by llama.cpp (/infill) and Ollama
+	// (suffix); other backends reject FIM requests.
+	Prefix string
+	Suffix string
+}
+
+// FIM reports whether r is a fill-in-the-middle request.
+func (r *Request) FIM() bool {
+	return r.Prefix != "" || r.Suffix != ""
+}
+
+// errFIMUnsupported is returned by backends without fill-in-the-middle.
+func errFIMUnsupported(backend string) error {
+	return fmt.Errorf("%s does not support fill-in-the-middle (prefix/suffix) requests", backend)
 }
 
+// errStreamLogProbsUnsupported is returned when logprobs are requested on a
+// streaming request; they are only returned by Complete.
+var errStreamLogProbsUnsupported = errors.New("logprobs are not supported on streaming requests")
+
 // Response represents an inference response from a backend
 type Response struct {
 	Text             string
 	PromptTokens     int
 	CompletionTokens int
+	LogProbs         json.RawMessage // choices[0].logprobs as returned by the backend; nil if not requested
 }
 
 // Config holds backend configuration
 type Config struct {
-	Type   string // "ollama", "llamacpp", "vllm", "lmstudio", "mlx"
+	Type   string // "ollama", "llamacpp", "vllm", "lmstudio", "mlx", "mock"
 	URL    string
 	Model  string
 	APIKey string // for backends that need auth
+
+	// OllamaContext makes the Ollama backend carry the /api/generate context
+	// between requests that share a SessionID, so each turn only needs the
+	// new prompt instead of the whole history.
+	OllamaContext bool
+
+	// OllamaKeepAlive is sent as keep_alive on every Ollama request: a
+	// duration like "30m", or a number of seconds where "-1" keeps the model
+	// loaded forever. Empty leaves Ollama's default (5m).
+	OllamaKeepAlive string
+
+	// LlamaCppChat sends prompt requests to llama.cpp's OpenAI-compatible
+	// /v1/chat/completions, which applies the model's chat template, instead
+	// of the raw /completion endpoint. Chat requests always use it.
+	LlamaCppChat bool
+
+	// CompletionsPath and ChatPath replace the prompt-completion and chat
+	// endpoint paths appended to URL, for servers behind a reverse proxy
+	// that remaps them. Empty keeps the backend's default: /api/generate and
+	// /api/chat for Ollama, /completion and /v1/chat/completions for
+	// llama.cpp, /v1/completions and /v1/chat/completions otherwise.
+	// InfillPath does the same for llama.cpp's /infill. Health checks and
+	// model listing keep their default paths (/api/tags, /health,
+	// /v1/models), so the proxy must pass those through unchanged.
+	CompletionsPath string
+	ChatPath        string
+	InfillPath      string
+}
+
+// endpointPath returns path, or def if path is empty, with a leading slash.
+func endpointPath(path, def string) string {
+	if path == "" {
+		return def
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
 }
 
 // CheckInsecureAPIKey returns an error if an API key is being sent over
@@ -100,6 +176,43 @@ func IsConnectionError(err error) bool {
 	return false
 }
 
+// responseSchema unpacks an OpenAI response_format for backends with their
+// own structured-output fields. jsonMode reports whether JSON output was
+// requested at all; schema is the embedded schema for "json_schema" and nil
+// for plain "json_object".
+func responseSchema(rf json.RawMessage) (schema json.RawMessage, jsonMode bool) {
+	if len(rf) == 0 {
+		return nil, false
+	}
+	var format struct {
+		Type       string `json:"type"`
+		JSONSchema struct {
+			Schema json.RawMessage `json:"schema"`
+		} `json:"json_schema"`
+	}
+	if err := json.Unmarshal(rf, &format); err != nil {
+		return nil, false
+	}
+	switch format.Type {
+	case "json_schema":
+		return format.JSONSchema.Schema, true
+	case "json_object":
+		return nil, true
+	}
+	return nil, false
+}
+
+// maxStreamLineSize bounds a single streamed line (an SSE "data:" event or an
+// NDJSON object). bufio.Scanner's 64KB default is too small for long
+// generations that arrive in one event, and exceeding it aborts the stream.
+const maxStreamLineSize = 16 * 1024 * 1024
+
+// newStreamScanner returns a line scanner for a streaming response body.
+func newStreamScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+	return scanner
+}
 
 // openAIChatRequest is the OpenAI-compatible chat completions request format.
 // Used by vLLM, llama.cpp, LM Studio, and MLX when messages are present.
@@ -109,7 +222,14 @@ type openAIChatRequest struct {
 	MaxTokens   int             `json:"max_tokens,omitempty"`
 	Temperature float64         `json:"temperature,omitempty"`
 	TopP        float64         `json:"top_p,omitempty"`
+	Stop        []string        `json:"stop,omitempty"`
+	Seed        *int            `json:"seed,omitempty"`
+	LogProbs    bool            `json:"logprobs,omitempty"`
+	TopLogProbs *int            `json:"top_logprobs,omitempty"`
 	Stream      bool            `json:"stream"`
+
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+	Grammar        string          `json:"grammar,omitempty"` // llama.cpp extension
 }
 
 // openAIChatResponse is the OpenAI-compatible chat completions response format.
@@ -124,8 +244,9 @@ type openAIChatResponse struct {
 		Delta struct {
 			Content string `json:"content"`
 		} `json:"delta"`
-		Index        int    `json:"index"`
-		FinishReason string `json:"finish_reason"`
+		Index        int             `json:"index"`
+		FinishReason string          `json:"finish_reason"`
+		LogProbs     json.RawMessage `json:"logprobs,omitempty"`
 	} `json:"choices"`
 	Usage struct {
 		PromptTokens     int `json:"prompt_tokens"`
@@ -147,6 +268,8 @@ func New(cfg Config) (Backend, error) {
 		return NewLMStudio(cfg)
 	case "mlx":
 		return NewMLX(cfg)
+	case "mock":
+		return NewMock(cfg)
 	default:
 		return nil, fmt.Errorf("unknown backend type: %s", cfg.Type)
 	}
@@ -7,6 +7,8 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"syscall"
 	"testing"
 )
@@ -93,6 +95,7 @@ func TestNew_ValidTypes(t *testing.T) {
 		{"vllm", "vllm"},
 		{"lmstudio", "lmstudio"},
 		{"mlx", "mlx"},
+		{"mock", "mock"},
 	}
 
 	for _, tc := range cases {
@@ -263,3 +266,746 @@ func TestLlamaCpp_ListModels(t *testing.T) {
 	}
 }
 
+func TestLlamaCpp_Complete_ForwardsStop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body llamaCppRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Stop) != 2 || body.Stop[0] != "\n\n" || body.Stop[1] != "###" {
+			t.Errorf("Stop = %q, want [\"\\n\\n\" \"###\"]", body.Stop)
+		}
+		json.NewEncoder(w).Encode(llamaCppResponse{Content: "ok", Stop: true})
+	}))
+	defer srv.Close()
+
+	b, _ := NewLlamaCpp(Config{URL: srv.URL})
+	if _, err := b.Complete(context.Background(), &Request{Prompt: "test", Stop: []string{"\n\n", "###"}}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+}
+
+func TestOllama_Complete_ForwardsStop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ollamaRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Options.Stop) != 1 || body.Options.Stop[0] != "END" {
+			t.Errorf("Options.Stop = %q, want [\"END\"]", body.Options.Stop)
+		}
+		json.NewEncoder(w).Encode(ollamaResponse{Response: "ok", Done: true})
+	}))
+	defer srv.Close()
+
+	b, _ := NewOllama(Config{URL: srv.URL, Model: "llama3"})
+	if _, err := b.Complete(context.Background(), &Request{Prompt: "test", Stop: []string{"END"}}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+}
+
+func TestVLLM_Complete_Seed(t *testing.T) {
+	var raw map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw = nil
+		json.NewDecoder(r.Body).Decode(&raw)
+		fmt.Fprint(w, `{"choices":[{"text":"ok","finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	b, _ := NewVLLM(Config{URL: srv.URL, Model: "m"})
+
+	seed := 42
+	if _, err := b.Complete(context.Background(), &Request{Prompt: "test", Seed: &seed}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if raw["seed"] != float64(42) {
+		t.Errorf("seed = %v, want 42", raw["seed"])
+	}
+
+	if _, err := b.Complete(context.Background(), &Request{Prompt: "test"}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, ok := raw["seed"]; ok {
+		t.Errorf("seed should be omitted when unset, got %v", raw["seed"])
+	}
+}
+
+func TestVLLM_Stream_KeepsWhitespaceTokens(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"text\":\"Hello\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"text\":\" \"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"text\":\"world\",\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	b, _ := NewVLLM(Config{URL: srv.URL, Model: "m"})
+
+	var tokens []string
+	resp, err := b.Stream(context.Background(), &Request{Prompt: "test"}, func(token string, done bool) error {
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if resp.Text != "Hello world" {
+		t.Errorf("Text = %q, want %q", resp.Text, "Hello world")
+	}
+	if len(tokens) != 3 || tokens[1] != " " {
+		t.Errorf("tokens = %q, want lone-space token preserved", tokens)
+	}
+}
+
+func TestVLLM_Stream_SpecCompliantSSE(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\r\n\r\n")
+		fmt.Fprint(w, "event: message\nid: 1\ndata: {\"choices\":\n")
+		fmt.Fprint(w, "data: [{\"text\":\"Hello\"}]}\n\n")
+		fmt.Fprint(w, "event: message\ndata:{\"choices\":[{\"text\":\" world\",\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "event: done\n\n") // no data: not dispatched
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	b, _ := NewVLLM(Config{URL: srv.URL, Model: "m"})
+
+	var tokens []string
+	resp, err := b.Stream(context.Background(), &Request{Prompt: "test"}, func(token string, done bool) error {
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if resp.Text != "Hello world" {
+		t.Errorf("Text = %q, want %q", resp.Text, "Hello world")
+	}
+	if len(tokens) != 2 {
+		t.Errorf("tokens = %q, want 2", tokens)
+	}
+}
+
+func TestSSEReader(t *testing.T) {
+	stream := "event: a\ndata: one\ndata:  two\n\n" +
+		"retry: 100\n\n" +
+		"data\n\n" +
+		"data: last"
+	r := newSSEReader(strings.NewReader(stream))
+
+	type event struct{ name, data string }
+	var got []event
+	for r.Next() {
+		got = append(got, event{r.Event(), r.Data()})
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	want := []event{{"a", "one\n two"}, {"", ""}, {"", "last"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestVLLM_Stream_NamedEvents(t *testing.T) {
+	chunk := func(text, finish string) string {
+		return fmt.Sprintf(`{"choices":[{"text":%q,"finish_reason":%q}]}`, text, finish)
+	}
+	tests := []struct {
+		name    string
+		stream  string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "non-message events skipped",
+			stream: "data: " + chunk("Hello", "") + "\n\n" +
+				"event: metrics\ndata: " + chunk(" ignored", "") + "\n\n" +
+				"event: message\ndata: " + chunk(" world", "stop") + "\n\n" +
+				"data: [DONE]\n\n",
+			want: "Hello world",
+		},
+		{
+			name: "error event",
+			stream: "data: " + chunk("Hello", "") + "\n\n" +
+				"event: error\ndata: {\"error\":\"Input validation error\",\"error_type\":\"validation\"}\n\n",
+			wantErr: "vllm stream error: Input validation error",
+		},
+		{
+			name: "OpenAI-style error event",
+			stream: "event: error\ndata: {\"error\":{\"message\":\"out of memory\"}}\n\n",
+			wantErr: "vllm stream error: out of memory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, tt.stream)
+			}))
+			defer srv.Close()
+
+			b, _ := NewVLLM(Config{URL: srv.URL, Model: "m"})
+			resp, err := b.Stream(context.Background(), &Request{Prompt: "hi"}, func(string, bool) error { return nil })
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Stream err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+			if resp.Text != tt.want {
+				t.Errorf("Text = %q, want %q", resp.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestLlamaCpp_Stream_LargeEvent(t *testing.T) {
+	big := strings.Repeat("x", 200*1024) // well past bufio.Scanner's 64KB default
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		data, _ := json.Marshal(llamaCppResponse{Content: big, Stop: true})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}))
+	defer srv.Close()
+
+	b, _ := NewLlamaCpp(Config{URL: srv.URL})
+	resp, err := b.Stream(context.Background(), &Request{Prompt: "test"}, func(token string, done bool) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if len(resp.Text) != len(big) {
+		t.Errorf("len(Text) = %d, want %d", len(resp.Text), len(big))
+	}
+}
+
+func TestOllama_SessionContext(t *testing.T) {
+	var gotContexts [][]int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ollamaRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		gotContexts = append(gotContexts, body.Context)
+		json.NewEncoder(w).Encode(ollamaResponse{Response: "ok", Done: true, Context: []int{len(gotContexts)}})
+	}))
+	defer srv.Close()
+
+	b, _ := NewOllama(Config{URL: srv.URL, Model: "llama3", OllamaContext: true})
+	for _, sid := range []string{"s1", "s1", "s2", ""} {
+		if _, err := b.Complete(context.Background(), &Request{Prompt: "hi", SessionID: sid}); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+	}
+
+	if gotContexts[0] != nil {
+		t.Errorf("first turn context = %v, want none", gotContexts[0])
+	}
+	if len(gotContexts[1]) != 1 || gotContexts[1][0] != 1 {
+		t.Errorf("second turn of s1 context = %v, want [1]", gotContexts[1])
+	}
+	if gotContexts[2] != nil || gotContexts[3] != nil {
+		t.Errorf("new or empty session should send no context, got %v and %v", gotContexts[2], gotContexts[3])
+	}
+}
+
+func TestOllama_SessionContextDisabled(t *testing.T) {
+	var lastContext []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ollamaRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		lastContext = body.Context
+		json.NewEncoder(w).Encode(ollamaResponse{Response: "ok", Done: true, Context: []int{7}})
+	}))
+	defer srv.Close()
+
+	b, _ := NewOllama(Config{URL: srv.URL, Model: "llama3"})
+	for i := 0; i < 2; i++ {
+		b.Complete(context.Background(), &Request{Prompt: "hi", SessionID: "s1"})
+	}
+	if lastContext != nil {
+		t.Errorf("context sent without OllamaContext: %v", lastContext)
+	}
+}
+
+func TestOllamaSessions_EvictsOldest(t *testing.T) {
+	s := newOllamaSessions(2)
+	s.put("a", []int{1})
+	s.put("b", []int{2})
+	s.put("a", []int{3}) // update does not change eviction order
+	s.put("c", []int{4})
+
+	if s.get("a") != nil {
+		t.Error("oldest session a should have been evicted")
+	}
+	if got := s.get("b"); len(got) != 1 || got[0] != 2 {
+		t.Errorf("get(b) = %v, want [2]", got)
+	}
+	if got := s.get("c"); len(got) != 1 || got[0] != 4 {
+		t.Errorf("get(c) = %v, want [4]", got)
+	}
+}
+
+func TestVLLM_Complete_LogProbs(t *testing.T) {
+	var raw map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw = nil
+		json.NewDecoder(r.Body).Decode(&raw)
+		fmt.Fprint(w, `{"choices":[{"text":"ok","finish_reason":"stop","logprobs":{"tokens":["ok"],"token_logprobs":[-0.1]}}]}`)
+	}))
+	defer srv.Close()
+
+	b, _ := NewVLLM(Config{URL: srv.URL, Model: "m"})
+
+	on, top := true, 3
+	resp, err := b.Complete(context.Background(), &Request{Prompt: "test", LogProbs: &on, TopLogProbs: &top})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if raw["logprobs"] != float64(3) {
+		t.Errorf("request logprobs = %v, want 3", raw["logprobs"])
+	}
+	if !strings.Contains(string(resp.LogProbs), `"token_logprobs":[-0.1]`) {
+		t.Errorf("LogProbs = %s, want backend logprobs passed through", resp.LogProbs)
+	}
+
+	if _, err := b.Complete(context.Background(), &Request{Prompt: "test"}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if _, ok := raw["logprobs"]; ok {
+		t.Errorf("logprobs should be omitted when not requested, got %v", raw["logprobs"])
+	}
+}
+
+func TestVLLM_Stream_RejectsLogProbs(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	b, _ := NewVLLM(Config{URL: srv.URL, Model: "m"})
+	noop := func(string, bool) error { return nil }
+
+	on := true
+	for _, req := range []*Request{
+		{Prompt: "test", LogProbs: &on},
+		{Messages: json.RawMessage(`[{"role":"user","content":"hi"}]`), LogProbs: &on},
+	} {
+		if _, err := b.Stream(context.Background(), req, noop); err == nil || !strings.Contains(err.Error(), "logprobs") {
+			t.Errorf("Stream with logprobs: err = %v, want a logprobs error", err)
+		}
+	}
+	if called {
+		t.Error("backend was called for a rejected request")
+	}
+
+	off := false
+	if _, err := b.Stream(context.Background(), &Request{Prompt: "test", LogProbs: &off}, noop); err != nil {
+		t.Errorf("Stream with logprobs off: %v", err)
+	}
+}
+
+func TestMock_EchoAndFixed(t *testing.T) {
+	b, err := New(Config{Type: "mock"})
+	if err != nil {
+		t.Fatalf("New(mock): %v", err)
+	}
+	resp, err := b.Complete(context.Background(), &Request{Prompt: "say hello"})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Text != "say hello" || resp.PromptTokens != 2 || resp.CompletionTokens != 2 {
+		t.Errorf("echo = %+v, want text %q with 2/2 tokens", resp, "say hello")
+	}
+
+	fixed, _ := NewMock(Config{URL: "mock://?text=canned+reply"})
+	resp, _ = fixed.Complete(context.Background(), &Request{Prompt: "anything"})
+	if resp.Text != "canned reply" {
+		t.Errorf("fixed Text = %q, want %q", resp.Text, "canned reply")
+	}
+}
+
+func TestMock_EchoesLastMessage(t *testing.T) {
+	b, _ := NewMock(Config{})
+	msgs := json.RawMessage(`[{"role":"user","content":"first"},{"role":"user","content":"second"}]`)
+	resp, _ := b.Complete(context.Background(), &Request{Messages: msgs})
+	if resp.Text != "second" {
+		t.Errorf("Text = %q, want %q", resp.Text, "second")
+	}
+}
+
+func TestMock_Stream(t *testing.T) {
+	b, _ := NewMock(Config{URL: "mock://echo?delay=1ms"})
+
+	var tokens []string
+	var sawDone bool
+	resp, err := b.Stream(context.Background(), &Request{Prompt: "one two three"}, func(token string, done bool) error {
+		tokens = append(tokens, token)
+		sawDone = done
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if len(tokens) != 3 || !sawDone {
+		t.Errorf("tokens = %q (done=%v), want 3 tokens ending in done", tokens, sawDone)
+	}
+	if strings.Join(tokens, "") != resp.Text {
+		t.Errorf("tokens %q do not reassemble to %q", tokens, resp.Text)
+	}
+}
+
+func TestMock_StreamCancelled(t *testing.T) {
+	b, _ := NewMock(Config{URL: "mock://echo?delay=1h"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.Stream(ctx, &Request{Prompt: "x"}, func(string, bool) error { return nil }); err == nil {
+		t.Fatal("expected error from cancelled stream")
+	}
+}
+
+func TestMock_InvalidDelay(t *testing.T) {
+	if _, err := NewMock(Config{URL: "mock://echo?delay=soon"}); err == nil {
+		t.Fatal("expected error for invalid delay")
+	}
+}
+
+func TestOllama_KeepAlive(t *testing.T) {
+	var raw map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw = nil
+		json.NewDecoder(r.Body).Decode(&raw)
+		json.NewEncoder(w).Encode(ollamaResponse{Response: "ok", Done: true})
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		setting string
+		want    any
+	}{
+		{"-1", float64(-1)},
+		{"30m", "30m"},
+	}
+	for _, tc := range cases {
+		b, err := NewOllama(Config{URL: srv.URL, Model: "llama3", OllamaKeepAlive: tc.setting})
+		if err != nil {
+			t.Fatalf("NewOllama(%q): %v", tc.setting, err)
+		}
+		b.Complete(context.Background(), &Request{Prompt: "hi"})
+		if raw["keep_alive"] != tc.want {
+			t.Errorf("keep_alive for %q = %#v, want %#v", tc.setting, raw["keep_alive"], tc.want)
+		}
+	}
+
+	b, _ := NewOllama(Config{URL: srv.URL, Model: "llama3"})
+	b.Complete(context.Background(), &Request{Prompt: "hi"})
+	if _, ok := raw["keep_alive"]; ok {
+		t.Error("keep_alive should be omitted by default")
+	}
+
+	if _, err := NewOllama(Config{OllamaKeepAlive: "forever"}); err == nil {
+		t.Error("expected error for invalid keep-alive")
+	}
+}
+
+func TestLlamaCpp_ChatEndpointForPrompts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content != "Say hello" {
+			t.Errorf("messages = %+v, want one user message with the prompt", req.Messages)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"Hello"}}],"usage":{"prompt_tokens":4,"completion_tokens":1}}`))
+	}))
+	defer srv.Close()
+
+	b, _ := NewLlamaCpp(Config{URL: srv.URL, LlamaCppChat: true})
+	resp, err := b.Complete(context.Background(), &Request{Prompt: "Say hello"})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Text != "Hello" || resp.PromptTokens != 4 || resp.CompletionTokens != 1 {
+		t.Errorf("resp = %+v", resp)
+	}
+}
+
+func TestLlamaCpp_ChatEndpointStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	b, _ := NewLlamaCpp(Config{URL: srv.URL, LlamaCppChat: true})
+	var got string
+	resp, err := b.Stream(context.Background(), &Request{Prompt: "Say hello"}, func(token string, done bool) error {
+		got += token
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if got != "Hello" || resp.Text != "Hello" {
+		t.Errorf("streamed %q, resp.Text %q, want Hello", got, resp.Text)
+	}
+}
+
+func TestResponseSchema(t *testing.T) {
+	cases := []struct {
+		in         string
+		wantSchema string
+		wantJSON   bool
+	}{
+		{``, ``, false},
+		{`{"type":"text"}`, ``, false},
+		{`{"type":"json_object"}`, ``, true},
+		{`{"type":"json_schema","json_schema":{"name":"x","schema":{"type":"array"}}}`, `{"type":"array"}`, true},
+		{`not json`, ``, false},
+	}
+	for _, tc := range cases {
+		schema, jsonMode := responseSchema(json.RawMessage(tc.in))
+		if string(schema) != tc.wantSchema || jsonMode != tc.wantJSON {
+			t.Errorf("responseSchema(%s) = (%s, %v), want (%s, %v)", tc.in, schema, jsonMode, tc.wantSchema, tc.wantJSON)
+		}
+	}
+}
+
+func TestOllama_Complete_JSONFormat(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ollamaRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		got = string(body.Format)
+		json.NewEncoder(w).Encode(ollamaResponse{Response: "{}", Done: true})
+	}))
+	defer srv.Close()
+
+	b, _ := NewOllama(Config{URL: srv.URL, Model: "llama3"})
+	req := &Request{Prompt: "test", ResponseFormat: json.RawMessage(`{"type":"json_object"}`)}
+	if _, err := b.Complete(context.Background(), req); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if got != `"json"` {
+		t.Errorf("format = %s, want \"json\"", got)
+	}
+}
+
+func TestLlamaCpp_Complete_GrammarAndSchema(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body llamaCppRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Grammar != `root ::= "yes" | "no"` {
+			t.Errorf("Grammar = %q", body.Grammar)
+		}
+		if string(body.JSONSchema) != `{"type":"object"}` {
+			t.Errorf("JSONSchema = %s, want {\"type\":\"object\"}", body.JSONSchema)
+		}
+		json.NewEncoder(w).Encode(llamaCppResponse{Content: "yes", Stop: true})
+	}))
+	defer srv.Close()
+
+	b, _ := NewLlamaCpp(Config{URL: srv.URL})
+	req := &Request{
+		Prompt:         "test",
+		Grammar:        `root ::= "yes" | "no"`,
+		ResponseFormat: json.RawMessage(`{"type":"json_object"}`),
+	}
+	if _, err := b.Complete(context.Background(), req); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+}
+
+func TestVLLM_CompleteChat_ForwardsResponseFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body openAIChatRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if string(body.ResponseFormat) != `{"type":"json_object"}` {
+			t.Errorf("response_format = %s", body.ResponseFormat)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"{}"}}]}`))
+	}))
+	defer srv.Close()
+
+	b, _ := NewVLLM(Config{URL: srv.URL, Model: "m"})
+	req := &Request{
+		Messages:       json.RawMessage(`[{"role":"user","content":"hi"}]`),
+		ResponseFormat: json.RawMessage(`{"type":"json_object"}`),
+	}
+	if _, err := b.Complete(context.Background(), req); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+}
+
+func TestLlamaCpp_Complete_Infill(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/infill" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var body llamaCppRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.InputPrefix != "func add(a, b int) int {\n" || body.InputSuffix != "\n}" {
+			t.Errorf("input_prefix = %q, input_suffix = %q", body.InputPrefix, body.InputSuffix)
+		}
+		json.NewEncoder(w).Encode(llamaCppResponse{Content: "\treturn a + b", Stop: true})
+	}))
+	defer srv.Close()
+
+	// FIM must bypass chat mode, which has no infill equivalent.
+	b, _ := NewLlamaCpp(Config{URL: srv.URL, LlamaCppChat: true})
+	resp, err := b.Complete(context.Background(), &Request{Prefix: "func add(a, b int) int {\n", Suffix: "\n}"})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Text != "\treturn a + b" {
+		t.Errorf("Text = %q", resp.Text)
+	}
+}
+
+func TestOllama_Complete_Suffix(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ollamaRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Prompt != "def f():" || body.Suffix != "\n    return x" {
+			t.Errorf("prompt = %q, suffix = %q", body.Prompt, body.Suffix)
+		}
+		json.NewEncoder(w).Encode(ollamaResponse{Response: "\n    x = 1", Done: true})
+	}))
+	defer srv.Close()
+
+	b, _ := NewOllama(Config{URL: srv.URL, Model: "codellama"})
+	if _, err := b.Complete(context.Background(), &Request{Prefix: "def f():", Suffix: "\n    return x"}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+}
+
+func TestVLLM_RejectsFIM(t *testing.T) {
+	b, _ := NewVLLM(Config{URL: "http://127.0.0.1:1", Model: "m"})
+	if _, err := b.Complete(context.Background(), &Request{Prefix: "a", Suffix: "b"}); err == nil {
+		t.Error("expected an error for a fill-in-the-middle request")
+	}
+}
+
+func TestHealth_SuggestsBackendForMismatchedServer(t *testing.T) {
+	// A vLLM server: serves /v1/models, not Ollama's /api/tags.
+	vllmSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"m","owned_by":"vllm"}]}`))
+	}))
+	defer vllmSrv.Close()
+
+	o, _ := NewOllama(Config{URL: vllmSrv.URL, Model: "m"})
+	err := o.Health(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "try --backend vllm") {
+		t.Errorf("ollama Health on a vLLM server = %v, want a --backend vllm hint", err)
+	}
+
+	// An Ollama server with its OpenAI-compatible API unavailable.
+	ollamaSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"version":"0.5.0"}`))
+	}))
+	defer ollamaSrv.Close()
+
+	l, _ := NewLlamaCpp(Config{URL: ollamaSrv.URL})
+	err = l.Health(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "try --backend ollama") {
+		t.Errorf("llama.cpp Health on an Ollama server = %v, want a --backend ollama hint", err)
+	}
+}
+
+func TestHealth_NoHintForUnknownServer(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	v, _ := NewVLLM(Config{URL: srv.URL, Model: "m"})
+	err := v.Health(context.Background())
+	if err == nil {
+		t.Fatal("expected error for 404")
+	}
+	if strings.Contains(err.Error(), "--backend") {
+		t.Errorf("unexpected hint for an unrecognized server: %v", err)
+	}
+}
+
+func TestPathOverrides(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/generate"):
+			fmt.Fprint(w, `{"response":"ok","done":true}`)
+		case strings.HasSuffix(r.URL.Path, "/chat"):
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"ok"},"done":true}`)
+		default:
+			fmt.Fprint(w, `{"choices":[{"text":"ok","message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	chat := &Request{Messages: json.RawMessage(`[{"role":"user","content":"hi"}]`)}
+
+	o, _ := NewOllama(Config{URL: srv.URL, Model: "m", CompletionsPath: "/ollama/api/generate", ChatPath: "ollama/api/chat"})
+	o.Complete(ctx, &Request{Prompt: "hi"})
+	o.Complete(ctx, chat)
+
+	v, _ := NewVLLM(Config{URL: srv.URL, Model: "m", CompletionsPath: "/proxy/completions", ChatPath: "/proxy/chat/completions"})
+	v.Complete(ctx, &Request{Prompt: "hi"})
+	v.Complete(ctx, chat)
+
+	l, _ := NewLlamaCpp(Config{URL: srv.URL, CompletionsPath: "/proxy/completion", InfillPath: "/proxy/infill"})
+	l.Complete(ctx, &Request{Prompt: "hi"})
+	l.Complete(ctx, &Request{Prefix: "def f(", Suffix: "):"})
+
+	want := []string{
+		"/ollama/api/generate", "/ollama/api/chat",
+		"/proxy/completions", "/proxy/chat/completions",
+		"/proxy/completion", "/proxy/infill",
+	}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestPathOverrides_DefaultsUnchanged(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		fmt.Fprint(w, `{"choices":[{"text":"ok","finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	b, _ := NewLMStudio(Config{URL: srv.URL, Model: "m"})
+	if _, err := b.Complete(context.Background(), &Request{Prompt: "hi"}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if path != "/v1/completions" {
+		t.Errorf("path = %q, want /v1/completions", path)
+	}
+}
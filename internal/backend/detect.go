@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// probeTimeout bounds each fingerprint request made by mismatchHint.
+const probeTimeout = 2 * time.Second
+
+// backendProbe recognizes one backend type from a response on path.
+type backendProbe struct {
+	backend string // --backend value
+	name    string // display name
+	path    string
+	match   func(body map[string]json.RawMessage) bool
+}
+
+// backendProbes identify a server by endpoints only one backend serves.
+var backendProbes = []backendProbe{
+	{"ollama", "Ollama", "/api/version", func(body map[string]json.RawMessage) bool {
+		_, ok := body["version"]
+		return ok
+	}},
+	{"llamacpp", "llama.cpp", "/props", func(body map[string]json.RawMessage) bool {
+		_, ok := body["default_generation_settings"]
+		return ok
+	}},
+	{"vllm", "vLLM", "/v1/models", func(body map[string]json.RawMessage) bool {
+		var models []struct {
+			OwnedBy string `json:"owned_by"`
+		}
+		json.Unmarshal(body["data"], &models)
+		return len(models) > 0 && models[0].OwnedBy == "vllm"
+	}},
+	{"lmstudio", "LM Studio", "/api/v0/models", func(body map[string]json.RawMessage) bool {
+		_, ok := body["data"]
+		return ok
+	}},
+}
+
+// mismatchHint is called when a health check against url suggests the
+// server is not the backend type self: its health endpoint returned 404, or
+// (for Ollama) a body that isn't a model list. It probes url for the other
+// backends and, if one of them answers, returns a hint naming the --backend
+// value to use, e.g. " (the server looks like Ollama; try --backend
+// ollama)". It returns "" when nothing matches.
+func mismatchHint(ctx context.Context, client *http.Client, url, apiKey, self string) string {
+	for _, p := range backendProbes {
+		if p.backend == self {
+			continue
+		}
+		if probeBackend(ctx, client, url+p.path, apiKey, p.match) {
+			return fmt.Sprintf(" (the server looks like %s; try --backend %s)", p.name, p.backend)
+		}
+	}
+	return ""
+}
+
+func probeBackend(ctx context.Context, client *http.Client, url, apiKey string, match func(map[string]json.RawMessage) bool) bool {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false
+	}
+	return match(body)
+}
@@ -1,14 +1,12 @@
 package backend
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -18,7 +16,12 @@ const defaultLlamaCppURL = "http://localhost:8080"
 type LlamaCpp struct {
 	url    string
 	model  string
+	chat   bool // send prompts to /v1/chat/completions
 	client *http.Client
+
+	completionPath string // default /completion
+	chatPath       string // default /v1/chat/completions
+	infillPath     string // default /infill
 }
 
 // NewLlamaCpp creates a new llama.cpp backend
@@ -31,9 +34,14 @@ func NewLlamaCpp(cfg Config) (*LlamaCpp, error) {
 	return &LlamaCpp{
 		url:   url,
 		model: cfg.Model,
+		chat:  cfg.LlamaCppChat,
 		client: &http.Client{
 			Timeout: 5 * time.Minute,
 		},
+
+		completionPath: endpointPath(cfg.CompletionsPath, "/completion"),
+		chatPath:       endpointPath(cfg.ChatPath, "/v1/chat/completions"),
+		infillPath:     endpointPath(cfg.InfillPath, "/infill"),
 	}, nil
 }
 
@@ -49,12 +57,20 @@ func (l *LlamaCpp) URL() string {
 
 // llamaCppRequest is the llama.cpp server request format
 type llamaCppRequest struct {
-	Model       string  `json:"model,omitempty"`
-	Prompt      string  `json:"prompt"`
-	NPredict    int     `json:"n_predict,omitempty"`
-	Temperature float64 `json:"temperature,omitempty"`
-	TopP        float64 `json:"top_p,omitempty"`
-	Stream      bool    `json:"stream"`
+	Model       string   `json:"model,omitempty"`
+	Prompt      string   `json:"prompt"`
+	NPredict    int      `json:"n_predict,omitempty"`
+	Temperature float64  `json:"temperature,omitempty"`
+	TopP        float64  `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stream      bool     `json:"stream"`
+
+	Grammar    string          `json:"grammar,omitempty"`     // GBNF
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"` // from response_format
+
+	InputPrefix string `json:"input_prefix,omitempty"` // /infill only
+	InputSuffix string `json:"input_suffix,omitempty"` // /infill only
 }
 
 // llamaCppResponse is the llama.cpp server response format
@@ -67,7 +83,7 @@ type llamaCppResponse struct {
 
 // Complete sends a prompt and returns the full completion
 func (l *LlamaCpp) Complete(ctx context.Context, req *Request) (*Response, error) {
-	if len(req.Messages) > 0 {
+	if (len(req.Messages) > 0 || l.chat) && !req.FIM() {
 		return l.completeChat(ctx, req)
 	}
 
@@ -77,15 +93,23 @@ func (l *LlamaCpp) Complete(ctx context.Context, req *Request) (*Response, error
 		NPredict:    req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      false,
+
+		Grammar: req.Grammar,
+
+		InputPrefix: req.Prefix,
+		InputSuffix: req.Suffix,
 	}
+	llamaReq.JSONSchema = llamaCppJSONSchema(req.ResponseFormat)
 
 	body, err := json.Marshal(llamaReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+"/completion", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+l.rawPath(req), bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -114,14 +138,48 @@ func (l *LlamaCpp) Complete(ctx context.Context, req *Request) (*Response, error
 	}, nil
 }
 
+// llamaCppJSONSchema maps response_format to /completion's json_schema
+// field. Plain JSON mode becomes a schema that accepts any object.
+func llamaCppJSONSchema(rf json.RawMessage) json.RawMessage {
+	schema, jsonMode := responseSchema(rf)
+	if jsonMode && schema == nil {
+		return json.RawMessage(`{"type":"object"}`)
+	}
+	return schema
+}
+
+// rawPath returns the raw completion endpoint for req: the infill path for
+// fill-in-the-middle, else the completion path.
+func (l *LlamaCpp) rawPath(req *Request) string {
+	if req.FIM() {
+		return l.infillPath
+	}
+	return l.completionPath
+}
+
+// chatMessages returns the request's chat messages, or its prompt as a
+// single user message.
+func chatMessages(req *Request) json.RawMessage {
+	if len(req.Messages) > 0 {
+		return req.Messages
+	}
+	msgs, _ := json.Marshal([]map[string]string{{"role": "user", "content": req.Prompt}})
+	return msgs
+}
+
 func (l *LlamaCpp) completeChat(ctx context.Context, req *Request) (*Response, error) {
 	chatReq := openAIChatRequest{
 		Model:       l.model,
-		Messages:    req.Messages,
+		Messages:    chatMessages(req),
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      false,
+
+		ResponseFormat: req.ResponseFormat,
+		Grammar:        req.Grammar,
 	}
 
 	body, err := json.Marshal(chatReq)
@@ -129,7 +187,7 @@ func (l *LlamaCpp) completeChat(ctx context.Context, req *Request) (*Response, e
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+"/v1/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+l.chatPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -165,7 +223,7 @@ func (l *LlamaCpp) completeChat(ctx context.Context, req *Request) (*Response, e
 
 // Stream sends a prompt and streams tokens via the callback
 func (l *LlamaCpp) Stream(ctx context.Context, req *Request, callback func(token string, done bool) error) (*Response, error) {
-	if len(req.Messages) > 0 {
+	if (len(req.Messages) > 0 || l.chat) && !req.FIM() {
 		return l.streamChat(ctx, req, callback)
 	}
 
@@ -175,15 +233,23 @@ func (l *LlamaCpp) Stream(ctx context.Context, req *Request, callback func(token
 		NPredict:    req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      true,
+
+		Grammar: req.Grammar,
+
+		InputPrefix: req.Prefix,
+		InputSuffix: req.Suffix,
 	}
+	llamaReq.JSONSchema = llamaCppJSONSchema(req.ResponseFormat)
 
 	body, err := json.Marshal(llamaReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+"/completion", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+l.rawPath(req), bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -203,14 +269,15 @@ func (l *LlamaCpp) Stream(ctx context.Context, req *Request, callback func(token
 	var fullText string
 	var promptTokens, completionTokens int
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
+	events := newSSEReader(resp.Body)
+	for events.Next() {
+		if err := events.EventError(); err != nil {
+			return nil, fmt.Errorf("llama.cpp stream error: %w", err)
+		}
+		if !events.IsMessage() {
 			continue
 		}
-
-		data := strings.TrimPrefix(line, "data: ")
+		data := events.Data()
 		var llamaResp llamaCppResponse
 		if err := json.Unmarshal([]byte(data), &llamaResp); err != nil {
 			continue
@@ -228,7 +295,7 @@ func (l *LlamaCpp) Stream(ctx context.Context, req *Request, callback func(token
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := events.Err(); err != nil {
 		return nil, fmt.Errorf("error reading stream: %w", err)
 	}
 
@@ -242,11 +309,16 @@ func (l *LlamaCpp) Stream(ctx context.Context, req *Request, callback func(token
 func (l *LlamaCpp) streamChat(ctx context.Context, req *Request, callback func(token string, done bool) error) (*Response, error) {
 	chatReq := openAIChatRequest{
 		Model:       l.model,
-		Messages:    req.Messages,
+		Messages:    chatMessages(req),
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      true,
+
+		ResponseFormat: req.ResponseFormat,
+		Grammar:        req.Grammar,
 	}
 
 	body, err := json.Marshal(chatReq)
@@ -254,7 +326,7 @@ func (l *LlamaCpp) streamChat(ctx context.Context, req *Request, callback func(t
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+"/v1/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+l.chatPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -274,14 +346,15 @@ func (l *LlamaCpp) streamChat(ctx context.Context, req *Request, callback func(t
 	var fullText string
 	var promptTokens, completionTokens int
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
+	events := newSSEReader(resp.Body)
+	for events.Next() {
+		if err := events.EventError(); err != nil {
+			return nil, fmt.Errorf("llama.cpp stream error: %w", err)
+		}
+		if !events.IsMessage() {
 			continue
 		}
-
-		data := strings.TrimPrefix(line, "data: ")
+		data := events.Data()
 		if data == "[DONE]" {
 			if err := callback("", true); err != nil {
 				return nil, err
@@ -310,7 +383,7 @@ func (l *LlamaCpp) streamChat(ctx context.Context, req *Request, callback func(t
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := events.Err(); err != nil {
 		return nil, fmt.Errorf("error reading stream: %w", err)
 	}
 
@@ -339,6 +412,9 @@ func (l *LlamaCpp) Health(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("llama.cpp returned status %d%s", resp.StatusCode, mismatchHint(ctx, l.client, l.url, "", "llamacpp"))
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("llama.cpp returned status %d", resp.StatusCode)
 	}
@@ -1,14 +1,12 @@
 package backend
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -20,6 +18,9 @@ type LMStudio struct {
 	model  string
 	apiKey string
 	client *http.Client
+
+	completionsPath string // default /v1/completions
+	chatPath        string // default /v1/chat/completions
 }
 
 // NewLMStudio creates a new LM Studio backend
@@ -40,6 +41,9 @@ func NewLMStudio(cfg Config) (*LMStudio, error) {
 		client: &http.Client{
 			Timeout: 5 * time.Minute,
 		},
+
+		completionsPath: endpointPath(cfg.CompletionsPath, "/v1/completions"),
+		chatPath:        endpointPath(cfg.ChatPath, "/v1/chat/completions"),
 	}, nil
 }
 
@@ -55,6 +59,9 @@ func (l *LMStudio) URL() string {
 
 // Complete sends a prompt and returns the full completion
 func (l *LMStudio) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if req.FIM() {
+		return nil, errFIMUnsupported(l.Name())
+	}
 	if len(req.Messages) > 0 {
 		return l.completeChat(ctx, req)
 	}
@@ -65,6 +72,8 @@ func (l *LMStudio) Complete(ctx context.Context, req *Request) (*Response, error
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      false,
 	}
 
@@ -73,7 +82,7 @@ func (l *LMStudio) Complete(ctx context.Context, req *Request) (*Response, error
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+"/v1/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+l.completionsPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -117,7 +126,11 @@ func (l *LMStudio) completeChat(ctx context.Context, req *Request) (*Response, e
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      false,
+
+		ResponseFormat: req.ResponseFormat,
 	}
 
 	body, err := json.Marshal(chatReq)
@@ -125,7 +138,7 @@ func (l *LMStudio) completeChat(ctx context.Context, req *Request) (*Response, e
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+"/v1/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+l.chatPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -164,6 +177,9 @@ func (l *LMStudio) completeChat(ctx context.Context, req *Request) (*Response, e
 
 // Stream sends a prompt and streams tokens via the callback
 func (l *LMStudio) Stream(ctx context.Context, req *Request, callback func(token string, done bool) error) (*Response, error) {
+	if req.FIM() {
+		return nil, errFIMUnsupported(l.Name())
+	}
 	if len(req.Messages) > 0 {
 		return l.streamChat(ctx, req, callback)
 	}
@@ -174,6 +190,8 @@ func (l *LMStudio) Stream(ctx context.Context, req *Request, callback func(token
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      true,
 	}
 
@@ -182,7 +200,7 @@ func (l *LMStudio) Stream(ctx context.Context, req *Request, callback func(token
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+"/v1/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+l.completionsPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -205,14 +223,15 @@ func (l *LMStudio) Stream(ctx context.Context, req *Request, callback func(token
 	var fullText string
 	var promptTokens, completionTokens int
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
+	events := newSSEReader(resp.Body)
+	for events.Next() {
+		if err := events.EventError(); err != nil {
+			return nil, fmt.Errorf("lmstudio stream error: %w", err)
+		}
+		if !events.IsMessage() {
 			continue
 		}
-
-		data := strings.TrimPrefix(line, "data: ")
+		data := events.Data()
 		if data == "[DONE]" {
 			if err := callback("", true); err != nil {
 				return nil, err
@@ -241,7 +260,7 @@ func (l *LMStudio) Stream(ctx context.Context, req *Request, callback func(token
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := events.Err(); err != nil {
 		return nil, fmt.Errorf("error reading stream: %w", err)
 	}
 
@@ -259,7 +278,11 @@ func (l *LMStudio) streamChat(ctx context.Context, req *Request, callback func(t
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      true,
+
+		ResponseFormat: req.ResponseFormat,
 	}
 
 	body, err := json.Marshal(chatReq)
@@ -267,7 +290,7 @@ func (l *LMStudio) streamChat(ctx context.Context, req *Request, callback func(t
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+"/v1/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.url+l.chatPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -290,14 +313,15 @@ func (l *LMStudio) streamChat(ctx context.Context, req *Request, callback func(t
 	var fullText string
 	var promptTokens, completionTokens int
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
+	events := newSSEReader(resp.Body)
+	for events.Next() {
+		if err := events.EventError(); err != nil {
+			return nil, fmt.Errorf("lmstudio stream error: %w", err)
+		}
+		if !events.IsMessage() {
 			continue
 		}
-
-		data := strings.TrimPrefix(line, "data: ")
+		data := events.Data()
 		if data == "[DONE]" {
 			if err := callback("", true); err != nil {
 				return nil, err
@@ -326,7 +350,7 @@ func (l *LMStudio) streamChat(ctx context.Context, req *Request, callback func(t
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := events.Err(); err != nil {
 		return nil, fmt.Errorf("error reading stream: %w", err)
 	}
 
@@ -389,6 +413,9 @@ func (l *LMStudio) Health(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("lmstudio returned status %d%s", resp.StatusCode, mismatchHint(ctx, l.client, l.url, l.apiKey, "lmstudio"))
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("lmstudio returned status %d", resp.StatusCode)
 	}
@@ -0,0 +1,150 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultMockURL = "mock://echo"
+
+// Mock implements the Backend interface without a model server. It is meant
+// for tests and for smoke-testing the publish flow.
+//
+// It is configured through its URL query:
+//
+//	text=<reply>   reply with a fixed string instead of echoing the prompt
+//	delay=<dur>    wait this long before each streamed token (e.g. 50ms)
+type Mock struct {
+	url   string
+	model string
+	text  string        // fixed reply; empty = echo the prompt
+	delay time.Duration // per-token delay when streaming
+}
+
+// NewMock creates a new mock backend
+func NewMock(cfg Config) (*Mock, error) {
+	rawURL := cfg.URL
+	if rawURL == "" {
+		rawURL = defaultMockURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mock URL %q: %w", rawURL, err)
+	}
+	q := u.Query()
+
+	m := &Mock{
+		url:   rawURL,
+		model: cfg.Model,
+		text:  q.Get("text"),
+	}
+	if d := q.Get("delay"); d != "" {
+		m.delay, err = time.ParseDuration(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mock delay %q: %w", d, err)
+		}
+	}
+	return m, nil
+}
+
+// Name returns the backend type
+func (m *Mock) Name() string {
+	return "mock"
+}
+
+// URL returns the backend endpoint URL
+func (m *Mock) URL() string {
+	return m.url
+}
+
+// Complete returns the canned reply
+func (m *Mock) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prompt := mockPrompt(req)
+	text := m.reply(prompt)
+	return &Response{
+		Text:             text,
+		PromptTokens:     len(strings.Fields(prompt)),
+		CompletionTokens: len(mockTokens(text)),
+	}, nil
+}
+
+// Stream sends the canned reply one word at a time
+func (m *Mock) Stream(ctx context.Context, req *Request, callback func(token string, done bool) error) (*Response, error) {
+	prompt := mockPrompt(req)
+	text := m.reply(prompt)
+	tokens := mockTokens(text)
+
+	for i, token := range tokens {
+		if m.delay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(m.delay):
+			}
+		} else if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := callback(token, i == len(tokens)-1); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Response{
+		Text:             text,
+		PromptTokens:     len(strings.Fields(prompt)),
+		CompletionTokens: len(tokens),
+	}, nil
+}
+
+// Health always succeeds
+func (m *Mock) Health(ctx context.Context) error {
+	return nil
+}
+
+// ListModels returns the configured model, if any
+func (m *Mock) ListModels(ctx context.Context) ([]string, error) {
+	if m.model == "" {
+		return nil, nil
+	}
+	return []string{m.model}, nil
+}
+
+func (m *Mock) reply(prompt string) string {
+	if m.text != "" {
+		return m.text
+	}
+	return prompt
+}
+
+// mockPrompt returns the text to echo: the prompt, or the content of the
+// last message when chat messages are present.
+func mockPrompt(req *Request) string {
+	if len(req.Messages) == 0 {
+		return req.Prompt
+	}
+	var msgs []struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(req.Messages, &msgs); err != nil || len(msgs) == 0 {
+		return req.Prompt
+	}
+	var content string
+	json.Unmarshal(msgs[len(msgs)-1].Content, &content) // multimodal parts echo as ""
+	return content
+}
+
+// mockTokens splits text into word tokens that concatenate back to text.
+func mockTokens(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.SplitAfter(text, " ")
+}
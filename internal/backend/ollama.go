@@ -1,7 +1,6 @@
 package backend
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/base64"
@@ -9,7 +8,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,9 +18,15 @@ const defaultOllamaURL = "http://localhost:11434"
 
 // Ollama implements the Backend interface for Ollama
 type Ollama struct {
-	url    string
-	model  string
-	client *http.Client
+	url      string
+	model    string
+	client   *http.Client
+	sessions *ollamaSessions // nil unless Config.OllamaContext is set
+
+	keepAlive any // keep_alive value: int seconds or duration string; nil = default
+
+	generatePath string // default /api/generate
+	chatPath     string // default /api/chat
 }
 
 // NewOllama creates a new Ollama backend
@@ -29,13 +36,50 @@ func NewOllama(cfg Config) (*Ollama, error) {
 		url = defaultOllamaURL
 	}
 
-	return &Ollama{
+	o := &Ollama{
 		url:   url,
 		model: cfg.Model,
 		client: &http.Client{
 			Timeout: 5 * time.Minute,
 		},
-	}, nil
+
+		generatePath: endpointPath(cfg.CompletionsPath, "/api/generate"),
+		chatPath:     endpointPath(cfg.ChatPath, "/api/chat"),
+	}
+	if cfg.OllamaContext {
+		o.sessions = newOllamaSessions(maxOllamaSessions)
+	}
+	if cfg.OllamaKeepAlive != "" {
+		keepAlive, err := parseKeepAlive(cfg.OllamaKeepAlive)
+		if err != nil {
+			return nil, err
+		}
+		o.keepAlive = keepAlive
+	}
+	return o, nil
+}
+
+// ollamaFormat maps response_format to Ollama's format field: "json" for
+// plain JSON mode, or the schema itself for structured outputs.
+func ollamaFormat(rf json.RawMessage) json.RawMessage {
+	schema, jsonMode := responseSchema(rf)
+	if jsonMode && schema == nil {
+		return json.RawMessage(`"json"`)
+	}
+	return schema
+}
+
+// parseKeepAlive converts a keep-alive setting into the JSON value Ollama
+// expects: a bare integer is seconds (negative = forever), anything else
+// must be a Go duration string.
+func parseKeepAlive(s string) (any, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	if _, err := time.ParseDuration(s); err != nil {
+		return nil, fmt.Errorf("invalid keep-alive %q: use seconds (-1 = forever) or a duration like 30m", s)
+	}
+	return s, nil
 }
 
 // Name returns the backend type
@@ -50,13 +94,21 @@ func (o *Ollama) URL() string {
 
 // ollamaRequest is the Ollama API request format
 type ollamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model   string `json:"model"`
+	Prompt  string `json:"prompt"`
+	Suffix  string `json:"suffix,omitempty"` // text after the cursor, for fill-in-the-middle
+	Stream  bool   `json:"stream"`
+	Context []int  `json:"context,omitempty"` // prior turn's context for the session
+
+	KeepAlive any             `json:"keep_alive,omitempty"`
+	Format    json.RawMessage `json:"format,omitempty"` // "json" or a JSON schema
+
 	Options struct {
-		NumPredict  int     `json:"num_predict,omitempty"`
-		Temperature float64 `json:"temperature,omitempty"`
-		TopP        float64 `json:"top_p,omitempty"`
+		NumPredict  int      `json:"num_predict,omitempty"`
+		Temperature float64  `json:"temperature,omitempty"`
+		TopP        float64  `json:"top_p,omitempty"`
+		Stop        []string `json:"stop,omitempty"`
+		Seed        *int     `json:"seed,omitempty"`
 	} `json:"options,omitempty"`
 }
 
@@ -75,10 +127,16 @@ type ollamaChatRequest struct {
 	Model    string          `json:"model"`
 	Messages json.RawMessage `json:"messages"`
 	Stream   bool            `json:"stream"`
-	Options  struct {
-		NumPredict  int     `json:"num_predict,omitempty"`
-		Temperature float64 `json:"temperature,omitempty"`
-		TopP        float64 `json:"top_p,omitempty"`
+
+	KeepAlive any             `json:"keep_alive,omitempty"`
+	Format    json.RawMessage `json:"format,omitempty"` // "json" or a JSON schema
+
+	Options struct {
+		NumPredict  int      `json:"num_predict,omitempty"`
+		Temperature float64  `json:"temperature,omitempty"`
+		TopP        float64  `json:"top_p,omitempty"`
+		Stop        []string `json:"stop,omitempty"`
+		Seed        *int     `json:"seed,omitempty"`
 	} `json:"options,omitempty"`
 }
 
@@ -96,25 +154,32 @@ type ollamaChatResponse struct {
 
 // Complete sends a prompt and returns the full completion
 func (o *Ollama) Complete(ctx context.Context, req *Request) (*Response, error) {
-	if len(req.Messages) > 0 {
+	if len(req.Messages) > 0 && !req.FIM() {
 		return o.completeChat(ctx, req)
 	}
 
 	ollamaReq := ollamaRequest{
 		Model:  o.model,
-		Prompt: req.Prompt,
+		Prompt: req.Prefix + req.Prompt,
+		Suffix: req.Suffix,
 		Stream: false,
+
+		KeepAlive: o.keepAlive,
+		Format:    ollamaFormat(req.ResponseFormat),
 	}
+	ollamaReq.Context = o.sessions.get(req.SessionID)
 	ollamaReq.Options.NumPredict = req.MaxTokens
 	ollamaReq.Options.Temperature = req.Temperature
 	ollamaReq.Options.TopP = req.TopP
+	ollamaReq.Options.Stop = req.Stop
+	ollamaReq.Options.Seed = req.Seed
 
 	body, err := json.Marshal(ollamaReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.url+"/api/generate", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.url+o.generatePath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -135,6 +200,7 @@ func (o *Ollama) Complete(ctx context.Context, req *Request) (*Response, error)
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	o.sessions.put(req.SessionID, ollamaResp.Context)
 
 	return &Response{
 		Text:             ollamaResp.Response,
@@ -152,17 +218,22 @@ func (o *Ollama) completeChat(ctx context.Context, req *Request) (*Response, err
 		Model:    o.model,
 		Messages: msgs,
 		Stream:   false,
+
+		KeepAlive: o.keepAlive,
+		Format:    ollamaFormat(req.ResponseFormat),
 	}
 	chatReq.Options.NumPredict = req.MaxTokens
 	chatReq.Options.Temperature = req.Temperature
 	chatReq.Options.TopP = req.TopP
+	chatReq.Options.Stop = req.Stop
+	chatReq.Options.Seed = req.Seed
 
 	body, err := json.Marshal(chatReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.url+"/api/chat", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.url+o.chatPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -193,25 +264,32 @@ func (o *Ollama) completeChat(ctx context.Context, req *Request) (*Response, err
 
 // Stream sends a prompt and streams tokens via the callback
 func (o *Ollama) Stream(ctx context.Context, req *Request, callback func(token string, done bool) error) (*Response, error) {
-	if len(req.Messages) > 0 {
+	if len(req.Messages) > 0 && !req.FIM() {
 		return o.streamChat(ctx, req, callback)
 	}
 
 	ollamaReq := ollamaRequest{
 		Model:  o.model,
-		Prompt: req.Prompt,
+		Prompt: req.Prefix + req.Prompt,
+		Suffix: req.Suffix,
 		Stream: true,
+
+		KeepAlive: o.keepAlive,
+		Format:    ollamaFormat(req.ResponseFormat),
 	}
+	ollamaReq.Context = o.sessions.get(req.SessionID)
 	ollamaReq.Options.NumPredict = req.MaxTokens
 	ollamaReq.Options.Temperature = req.Temperature
 	ollamaReq.Options.TopP = req.TopP
+	ollamaReq.Options.Stop = req.Stop
+	ollamaReq.Options.Seed = req.Seed
 
 	body, err := json.Marshal(ollamaReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.url+"/api/generate", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.url+o.generatePath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -231,7 +309,7 @@ func (o *Ollama) Stream(ctx context.Context, req *Request, callback func(token s
 	var fullText string
 	var promptTokens, completionTokens int
 
-	scanner := bufio.NewScanner(resp.Body)
+	scanner := newStreamScanner(resp.Body)
 	for scanner.Scan() {
 		var ollamaResp ollamaResponse
 		if err := json.Unmarshal(scanner.Bytes(), &ollamaResp); err != nil {
@@ -247,6 +325,7 @@ func (o *Ollama) Stream(ctx context.Context, req *Request, callback func(token s
 		if ollamaResp.Done {
 			promptTokens = ollamaResp.PromptEvalCount
 			completionTokens = ollamaResp.EvalCount
+			o.sessions.put(req.SessionID, ollamaResp.Context)
 		}
 	}
 
@@ -270,17 +349,22 @@ func (o *Ollama) streamChat(ctx context.Context, req *Request, callback func(tok
 		Model:    o.model,
 		Messages: msgs,
 		Stream:   true,
+
+		KeepAlive: o.keepAlive,
+		Format:    ollamaFormat(req.ResponseFormat),
 	}
 	chatReq.Options.NumPredict = req.MaxTokens
 	chatReq.Options.Temperature = req.Temperature
 	chatReq.Options.TopP = req.TopP
+	chatReq.Options.Stop = req.Stop
+	chatReq.Options.Seed = req.Seed
 
 	body, err := json.Marshal(chatReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.url+"/api/chat", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.url+o.chatPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -300,7 +384,7 @@ func (o *Ollama) streamChat(ctx context.Context, req *Request, callback func(tok
 	var fullText string
 	var promptTokens, completionTokens int
 
-	scanner := bufio.NewScanner(resp.Body)
+	scanner := newStreamScanner(resp.Body)
 	for scanner.Scan() {
 		var chatResp ollamaChatResponse
 		if err := json.Unmarshal(scanner.Bytes(), &chatResp); err != nil {
@@ -425,6 +509,9 @@ func (o *Ollama) Health(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("ollama returned status %d%s", resp.StatusCode, mismatchHint(ctx, o.client, o.url, "", "ollama"))
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("ollama returned status %d", resp.StatusCode)
 	}
@@ -435,7 +522,7 @@ func (o *Ollama) Health(ctx context.Context) error {
 		} `json:"models"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
-		return fmt.Errorf("failed to parse ollama models: %w", err)
+		return fmt.Errorf("failed to parse ollama models: %w%s", err, mismatchHint(ctx, o.client, o.url, "", "ollama"))
 	}
 
 	var available []string
@@ -504,3 +591,48 @@ func formatModelList(models []string) string {
 	}
 	return result
 }
+
+// maxOllamaSessions caps how many conversation contexts are kept in memory.
+const maxOllamaSessions = 256
+
+// ollamaSessions stores the /api/generate context returned for each session
+// so the next turn can resume from it. Oldest sessions are evicted first.
+// A nil *ollamaSessions is valid and stores nothing.
+type ollamaSessions struct {
+	mu    sync.Mutex
+	max   int
+	ctx   map[string][]int
+	order []string // session IDs, oldest first
+}
+
+func newOllamaSessions(max int) *ollamaSessions {
+	return &ollamaSessions{max: max, ctx: make(map[string][]int)}
+}
+
+// get returns the stored context for id, or nil.
+func (s *ollamaSessions) get(id string) []int {
+	if s == nil || id == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx[id]
+}
+
+// put stores the context for id, evicting the oldest session when full.
+func (s *ollamaSessions) put(id string, tokens []int) {
+	if s == nil || id == "" || len(tokens) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ctx[id]; !ok {
+		if len(s.order) >= s.max {
+			delete(s.ctx, s.order[0])
+			s.order = s.order[1:]
+		}
+		s.order = append(s.order, id)
+	}
+	s.ctx[id] = tokens
+}
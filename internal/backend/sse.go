@@ -0,0 +1,117 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sseReader splits a text/event-stream body into events per the SSE spec:
+// an event is the run of field lines up to a blank line, its "data" lines
+// are joined with newlines, and comments (":") and other fields such as
+// "id" and "retry" are ignored. Unlike the spec, a final event that is not
+// followed by a blank line is still delivered.
+type sseReader struct {
+	scanner *bufio.Scanner
+	event   string
+	data    string
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{scanner: newStreamScanner(r)}
+}
+
+// Next advances to the next event with data, reporting false at the end of
+// the stream or on a read error (see Err).
+func (s *sseReader) Next() bool {
+	var event string
+	var data strings.Builder
+	hasData := false
+
+	dispatch := func() bool {
+		if !hasData {
+			event = ""
+			return false
+		}
+		s.event, s.data = event, data.String()
+		return true
+	}
+
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			if dispatch() {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment, e.g. a keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		}
+	}
+	return s.scanner.Err() == nil && dispatch()
+}
+
+// Event returns the current event's name; empty for unnamed events, which
+// the spec treats as "message".
+func (s *sseReader) Event() string { return s.event }
+
+// IsMessage reports whether the current event is a normal "message" event.
+// Streams carry chunks in message events; other named events should be
+// skipped rather than decoded as chunks.
+func (s *sseReader) IsMessage() bool {
+	return s.event == "" || s.event == "message"
+}
+
+// EventError returns an error if the current event is an "error" event, as
+// sent by TGI and some OpenAI-compatible servers when generation fails
+// mid-stream. The message is taken from the event's JSON data when it has
+// one, else the data is used as is.
+func (s *sseReader) EventError() error {
+	if s.event != "error" {
+		return nil
+	}
+	var payload struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal([]byte(s.data), &payload) == nil {
+		var msg string
+		var nested struct {
+			Message string `json:"message"`
+		}
+		switch {
+		case json.Unmarshal(payload.Error, &msg) == nil && msg != "":
+			return errors.New(msg)
+		case json.Unmarshal(payload.Error, &nested) == nil && nested.Message != "":
+			return errors.New(nested.Message)
+		case payload.Message != "":
+			return errors.New(payload.Message)
+		}
+	}
+	if s.data == "" {
+		return errors.New("unknown error")
+	}
+	return errors.New(s.data)
+}
+
+// Data returns the current event's data lines, joined with "\n".
+func (s *sseReader) Data() string { return s.data }
+
+// Err returns the first read error, if any.
+func (s *sseReader) Err() error { return s.scanner.Err() }
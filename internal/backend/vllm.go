@@ -1,14 +1,12 @@
 package backend
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -20,6 +18,9 @@ type VLLM struct {
 	model  string
 	apiKey string
 	client *http.Client
+
+	completionsPath string // default /v1/completions
+	chatPath        string // default /v1/chat/completions
 }
 
 // NewVLLM creates a new vLLM backend
@@ -40,6 +41,9 @@ func NewVLLM(cfg Config) (*VLLM, error) {
 		client: &http.Client{
 			Timeout: 5 * time.Minute,
 		},
+
+		completionsPath: endpointPath(cfg.CompletionsPath, "/v1/completions"),
+		chatPath:        endpointPath(cfg.ChatPath, "/v1/chat/completions"),
 	}, nil
 }
 
@@ -55,12 +59,17 @@ func (v *VLLM) URL() string {
 
 // openAIRequest is the OpenAI-compatible request format
 type openAIRequest struct {
-	Model       string  `json:"model"`
-	Prompt      string  `json:"prompt"`
-	MaxTokens   int     `json:"max_tokens,omitempty"`
-	Temperature float64 `json:"temperature,omitempty"`
-	TopP        float64 `json:"top_p,omitempty"`
-	Stream      bool    `json:"stream"`
+	Model       string   `json:"model"`
+	Prompt      string   `json:"prompt"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature float64  `json:"temperature,omitempty"`
+	TopP        float64  `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	LogProbs    *int     `json:"logprobs,omitempty"` // completions API: number of top logprobs per token
+	Stream      bool     `json:"stream"`
+
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"` // honored by vLLM
 }
 
 // openAIResponse is the OpenAI-compatible response format
@@ -68,9 +77,10 @@ type openAIResponse struct {
 	ID      string `json:"id"`
 	Object  string `json:"object"`
 	Choices []struct {
-		Text         string `json:"text"`
-		Index        int    `json:"index"`
-		FinishReason string `json:"finish_reason"`
+		Text         string          `json:"text"`
+		Index        int             `json:"index"`
+		FinishReason string          `json:"finish_reason"`
+		LogProbs     json.RawMessage `json:"logprobs,omitempty"`
 	} `json:"choices"`
 	Usage struct {
 		PromptTokens     int `json:"prompt_tokens"`
@@ -81,6 +91,9 @@ type openAIResponse struct {
 
 // Complete sends a prompt and returns the full completion
 func (v *VLLM) Complete(ctx context.Context, req *Request) (*Response, error) {
+	if req.FIM() {
+		return nil, errFIMUnsupported(v.Name())
+	}
 	if len(req.Messages) > 0 {
 		return v.completeChat(ctx, req)
 	}
@@ -91,7 +104,12 @@ func (v *VLLM) Complete(ctx context.Context, req *Request) (*Response, error) {
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
+		LogProbs:    completionLogProbs(req),
 		Stream:      false,
+
+		ResponseFormat: req.ResponseFormat,
 	}
 
 	body, err := json.Marshal(vllmReq)
@@ -99,7 +117,7 @@ func (v *VLLM) Complete(ctx context.Context, req *Request) (*Response, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", v.url+"/v1/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", v.url+v.completionsPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -125,17 +143,33 @@ func (v *VLLM) Complete(ctx context.Context, req *Request) (*Response, error) {
 	}
 
 	text := ""
+	var logprobs json.RawMessage
 	if len(vllmResp.Choices) > 0 {
 		text = vllmResp.Choices[0].Text
+		logprobs = vllmResp.Choices[0].LogProbs
 	}
 
 	return &Response{
 		Text:             text,
 		PromptTokens:     vllmResp.Usage.PromptTokens,
 		CompletionTokens: vllmResp.Usage.CompletionTokens,
+		LogProbs:         logprobs,
 	}, nil
 }
 
+// completionLogProbs maps the chat-style LogProbs/TopLogProbs pair onto the
+// completions API, where logprobs is the number of alternatives per token.
+func completionLogProbs(req *Request) *int {
+	if req.LogProbs == nil || !*req.LogProbs {
+		return nil
+	}
+	n := 0
+	if req.TopLogProbs != nil {
+		n = *req.TopLogProbs
+	}
+	return &n
+}
+
 func (v *VLLM) completeChat(ctx context.Context, req *Request) (*Response, error) {
 	chatReq := openAIChatRequest{
 		Model:       v.model,
@@ -143,7 +177,13 @@ func (v *VLLM) completeChat(ctx context.Context, req *Request) (*Response, error
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
+		LogProbs:    req.LogProbs != nil && *req.LogProbs,
+		TopLogProbs: req.TopLogProbs,
 		Stream:      false,
+
+		ResponseFormat: req.ResponseFormat,
 	}
 
 	body, err := json.Marshal(chatReq)
@@ -151,7 +191,7 @@ func (v *VLLM) completeChat(ctx context.Context, req *Request) (*Response, error
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", v.url+"/v1/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", v.url+v.chatPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -177,19 +217,30 @@ func (v *VLLM) completeChat(ctx context.Context, req *Request) (*Response, error
 	}
 
 	text := ""
+	var logprobs json.RawMessage
 	if len(chatResp.Choices) > 0 {
 		text = chatResp.Choices[0].Message.Content
+		logprobs = chatResp.Choices[0].LogProbs
 	}
 
 	return &Response{
 		Text:             text,
 		PromptTokens:     chatResp.Usage.PromptTokens,
 		CompletionTokens: chatResp.Usage.CompletionTokens,
+		LogProbs:         logprobs,
 	}, nil
 }
 
 // Stream sends a prompt and streams tokens via the callback
 func (v *VLLM) Stream(ctx context.Context, req *Request, callback func(token string, done bool) error) (*Response, error) {
+	if req.FIM() {
+		return nil, errFIMUnsupported(v.Name())
+	}
+	// Streamed tokens carry no logprobs to the gateway, so rather than
+	// silently dropping them, refuse the request.
+	if req.LogProbs != nil && *req.LogProbs {
+		return nil, errStreamLogProbsUnsupported
+	}
 	if len(req.Messages) > 0 {
 		return v.streamChat(ctx, req, callback)
 	}
@@ -200,7 +251,11 @@ func (v *VLLM) Stream(ctx context.Context, req *Request, callback func(token str
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      true,
+
+		ResponseFormat: req.ResponseFormat,
 	}
 
 	body, err := json.Marshal(vllmReq)
@@ -208,7 +263,7 @@ func (v *VLLM) Stream(ctx context.Context, req *Request, callback func(token str
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", v.url+"/v1/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", v.url+v.completionsPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -231,14 +286,15 @@ func (v *VLLM) Stream(ctx context.Context, req *Request, callback func(token str
 	var fullText string
 	var promptTokens, completionTokens int
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
+	events := newSSEReader(resp.Body)
+	for events.Next() {
+		if err := events.EventError(); err != nil {
+			return nil, fmt.Errorf("vllm stream error: %w", err)
+		}
+		if !events.IsMessage() {
 			continue
 		}
-
-		data := strings.TrimPrefix(line, "data: ")
+		data := events.Data()
 		if data == "[DONE]" {
 			if err := callback("", true); err != nil {
 				return nil, err
@@ -267,7 +323,7 @@ func (v *VLLM) Stream(ctx context.Context, req *Request, callback func(token str
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := events.Err(); err != nil {
 		return nil, fmt.Errorf("error reading stream: %w", err)
 	}
 
@@ -285,7 +341,11 @@ func (v *VLLM) streamChat(ctx context.Context, req *Request, callback func(token
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Seed:        req.Seed,
 		Stream:      true,
+
+		ResponseFormat: req.ResponseFormat,
 	}
 
 	body, err := json.Marshal(chatReq)
@@ -293,7 +353,7 @@ func (v *VLLM) streamChat(ctx context.Context, req *Request, callback func(token
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", v.url+"/v1/chat/completions", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", v.url+v.chatPath, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -316,14 +376,15 @@ func (v *VLLM) streamChat(ctx context.Context, req *Request, callback func(token
 	var fullText string
 	var promptTokens, completionTokens int
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
+	events := newSSEReader(resp.Body)
+	for events.Next() {
+		if err := events.EventError(); err != nil {
+			return nil, fmt.Errorf("vllm stream error: %w", err)
+		}
+		if !events.IsMessage() {
 			continue
 		}
-
-		data := strings.TrimPrefix(line, "data: ")
+		data := events.Data()
 		if data == "[DONE]" {
 			if err := callback("", true); err != nil {
 				return nil, err
@@ -352,7 +413,7 @@ func (v *VLLM) streamChat(ctx context.Context, req *Request, callback func(token
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := events.Err(); err != nil {
 		return nil, fmt.Errorf("error reading stream: %w", err)
 	}
 
@@ -415,6 +476,9 @@ func (v *VLLM) Health(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("vllm returned status %d%s", resp.StatusCode, mismatchHint(ctx, v.client, v.url, v.apiKey, "vllm"))
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("vllm returned status %d", resp.StatusCode)
 	}
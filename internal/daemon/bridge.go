@@ -9,6 +9,7 @@ import (
 
 	"github.com/cllmhub/cllmhub-cli/internal/auth"
 	"github.com/cllmhub/cllmhub-cli/internal/backend"
+	"github.com/cllmhub/cllmhub-cli/internal/hub"
 	"github.com/cllmhub/cllmhub-cli/internal/provider"
 )
 
@@ -19,6 +20,7 @@ type Bridge struct {
 	provider    *provider.Provider
 	cancel      context.CancelFunc
 	done        chan struct{}
+	conn        *connState // nil until the provider is created
 }
 
 // BridgeManager manages all active bridges.
@@ -27,14 +29,19 @@ type BridgeManager struct {
 	bridges map[string]*Bridge
 	logger  *slog.Logger
 	watch   bool
+
+	watchInterval time.Duration
 }
 
-// NewBridgeManager creates a new bridge manager.
-func NewBridgeManager(logger *slog.Logger, watch bool) *BridgeManager {
+// NewBridgeManager creates a new bridge manager. watchInterval is the
+// proactive health check period when watch is set; 0 uses the default.
+func NewBridgeManager(logger *slog.Logger, watch bool, watchInterval time.Duration) *BridgeManager {
 	return &BridgeManager{
 		bridges: make(map[string]*Bridge),
 		logger:  logger,
 		watch:   watch,
+
+		watchInterval: watchInterval,
 	}
 }
 
@@ -69,20 +76,51 @@ func (bm *BridgeManager) StartBridge(spec PublishModelSpec, hubURL, token string
 		Backend: backend.Config{
 			Type:   spec.BackendType,
 			URL:    spec.BackendURL,
-			Model:  spec.Name,
+			Model:  spec.ServedModel(),
 			APIKey: spec.BackendAPIKey,
+
+			OllamaContext:   spec.OllamaContext,
+			OllamaKeepAlive: spec.OllamaKeepAlive,
+			LlamaCppChat:    spec.LlamaCppChat,
+
+			CompletionsPath: spec.CompletionsPath,
+			ChatPath:        spec.ChatPath,
+			InfillPath:      spec.InfillPath,
 		},
 		HubURL:        hubURL,
 		MaxConcurrent: spec.MaxConcurrent,
 		TokenManager:  tokenMgr,
 		Logger:        bm.logger,
 		Watch:         bm.watch,
+		WatchInterval: bm.watchInterval,
+
+		MaxTokensLimit: spec.MaxTokensLimit,
+		RequestTimeout: spec.RequestTimeout,
+
+		HealthRetries:       spec.HealthRetries,
+		HealthRetryInterval: spec.HealthRetryInterval,
+
+		MaxQueue: spec.MaxQueue,
+
+		ProviderID: spec.ProviderID,
+
+		StreamCoalesce: spec.StreamCoalesce,
+	}
+	conn := &connState{}
+	cfg.Events = conn
+	if cfg.ProviderID == "" {
+		id, err := provider.StableProviderID(spec.Name)
+		if err != nil {
+			bm.logger.Warn("cannot load stable provider ID, using a random one", "model", spec.Name, "error", err)
+		}
+		cfg.ProviderID = id
 	}
 
 	p, err := provider.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create provider for %q: %w", spec.Name, err)
 	}
+	conn.connected.Store(true) // New registers the model with the hub
 
 	ctx, cancel := context.WithCancel(context.Background())
 	done := make(chan struct{})
@@ -93,6 +131,7 @@ func (bm *BridgeManager) StartBridge(spec PublishModelSpec, hubURL, token string
 		provider:    p,
 		cancel:      cancel,
 		done:        done,
+		conn:        conn,
 	}
 
 	bm.mu.Lock()
@@ -124,7 +163,7 @@ func (bm *BridgeManager) StopBridge(model string) error {
 		return fmt.Errorf("model %q is not published", model)
 	}
 
-	bridge.provider.Stop()
+	bridge.provider.StopWithReason(hub.UnregisterUnpublish)
 	bridge.cancel()
 
 	// Wait for the provider goroutine to exit, but don't block forever.
@@ -143,6 +182,11 @@ func (bm *BridgeManager) StopBridge(model string) error {
 
 // StopAll stops all bridges.
 func (bm *BridgeManager) StopAll() {
+	bm.stopAll(hub.UnregisterShutdown)
+}
+
+// stopAll stops all bridges, giving the gateway reason for each unregister.
+func (bm *BridgeManager) stopAll(reason string) {
 	bm.mu.RLock()
 	bridges := make([]*Bridge, 0, len(bm.bridges))
 	for _, b := range bm.bridges {
@@ -151,7 +195,7 @@ func (bm *BridgeManager) StopAll() {
 	bm.mu.RUnlock()
 
 	for _, b := range bridges {
-		b.provider.Stop()
+		b.provider.StopWithReason(reason)
 		b.cancel()
 
 		select {
@@ -162,6 +206,53 @@ func (bm *BridgeManager) StopAll() {
 	}
 }
 
+// SetPaused pauses or resumes the bridge for a model. A paused bridge stays
+// connected and finishes in-flight requests but refuses new ones.
+func (bm *BridgeManager) SetPaused(model string, paused bool) error {
+	bm.mu.RLock()
+	bridge, exists := bm.bridges[model]
+	bm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("model %q is not published", model)
+	}
+	if bridge.provider == nil {
+		return fmt.Errorf("model %q is still starting", model)
+	}
+
+	if bridge.provider.SetPaused(paused) {
+		if paused {
+			bm.logger.Info("bridge paused", "model", model)
+		} else {
+			bm.logger.Info("bridge resumed", "model", model)
+		}
+	}
+	return nil
+}
+
+// TogglePaused pauses every bridge, or resumes them all if every bridge is
+// already paused. It returns the new state.
+func (bm *BridgeManager) TogglePaused() bool {
+	bm.mu.RLock()
+	providers := make([]*provider.Provider, 0, len(bm.bridges))
+	allPaused := true
+	for _, b := range bm.bridges {
+		if b.provider == nil {
+			continue
+		}
+		providers = append(providers, b.provider)
+		if !b.provider.Paused() {
+			allPaused = false
+		}
+	}
+	bm.mu.RUnlock()
+
+	for _, p := range providers {
+		p.SetPaused(!allPaused)
+	}
+	return !allPaused
+}
+
 // ProviderID returns the hub provider ID for a published model.
 // Returns empty string if the model is not published or not yet registered.
 func (bm *BridgeManager) ProviderID(model string) string {
@@ -188,6 +279,10 @@ type BridgeInfo struct {
 	Backend       string // "ollama", "vllm", etc.
 	ProviderID    string // cLLMHub provider ID
 	MaxConcurrent int    // concurrent request slots
+	Paused        bool   // refusing new requests while in-flight ones finish
+
+	RequestLatencyMs  *provider.HistogramSnapshot // nil until the provider is running
+	HeartbeatFailures int64                       // failed heartbeat sends since start
 }
 
 // PublishedModels returns the list of currently published model names.
@@ -209,19 +304,43 @@ func (bm *BridgeManager) PublishedModelsWithBackend() []BridgeInfo {
 
 	infos := make([]BridgeInfo, 0, len(bm.bridges))
 	for _, b := range bm.bridges {
-		providerID := ""
-		if b.provider != nil {
-			providerID = b.provider.Status().ProviderID
-		}
-		var maxConcurrent int
+		info := BridgeInfo{Name: b.model, Backend: b.backendType}
 		if b.provider != nil {
-			maxConcurrent = b.provider.Status().MaxConcurrent
+			status := b.provider.Status()
+			info.ProviderID = status.ProviderID
+			info.MaxConcurrent = status.MaxConcurrent
+			info.Paused = b.provider.Paused()
+			info.RequestLatencyMs = &status.RequestLatencyMs
+			info.HeartbeatFailures = status.HeartbeatFailures
 		}
-		infos = append(infos, BridgeInfo{Name: b.model, Backend: b.backendType, ProviderID: providerID, MaxConcurrent: maxConcurrent})
+		infos = append(infos, info)
 	}
 	return infos
 }
 
+// Health returns the health of each bridge, keyed by model: healthStarting
+// while it is being published, then healthOK, healthDegraded, or
+// healthDisconnected.
+func (bm *BridgeManager) Health() map[string]string {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+
+	health := make(map[string]string, len(bm.bridges))
+	for name, b := range bm.bridges {
+		switch {
+		case b.provider == nil:
+			health[name] = healthStarting
+		case !b.conn.connected.Load():
+			health[name] = healthDisconnected
+		case b.provider.Status().Status == hub.StatusDegraded:
+			health[name] = healthDegraded
+		default:
+			health[name] = healthOK
+		}
+	}
+	return health
+}
+
 // Count returns the number of active bridges.
 func (bm *BridgeManager) Count() int {
 	bm.mu.RLock()
@@ -12,6 +12,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/cllmhub/cllmhub-cli/internal/provider"
 )
 
 // Client communicates with the daemon over the Unix socket.
@@ -86,6 +88,12 @@ func IsRunning() (bool, int) {
 
 // doRequest creates an HTTP request with the daemon auth token.
 func (c *Client) doRequest(method, url string, body io.Reader) (*http.Response, error) {
+	return c.doRequestTimeout(c.httpClient.Timeout, method, url, body)
+}
+
+// doRequestTimeout is doRequest with a timeout other than the client default,
+// for calls that wait on backends.
+func (c *Client) doRequestTimeout(timeout time.Duration, method, url string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
@@ -94,7 +102,10 @@ func (c *Client) doRequest(method, url string, body io.Reader) (*http.Response,
 		req.Header.Set("Content-Type", "application/json")
 	}
 	req.Header.Set("Authorization", "Bearer "+c.authToken)
-	return c.httpClient.Do(req)
+
+	hc := *c.httpClient
+	hc.Timeout = timeout
+	return hc.Do(req)
 }
 
 // Health checks if the daemon is responding.
@@ -143,7 +154,7 @@ func (c *Client) Stop() error {
 // Publish requests the daemon to publish one or more models.
 func (c *Client) Publish(specs []PublishModelSpec) (*PublishResponse, error) {
 	body, _ := json.Marshal(PublishRequest{Models: specs})
-	resp, err := c.doRequest("POST", "http://daemon/api/publish", bytes.NewReader(body))
+	resp, err := c.doRequestTimeout(publishTimeout(specs), "POST", "http://daemon/api/publish", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("cannot connect to daemon: %w", err)
 	}
@@ -161,6 +172,16 @@ func (c *Client) Publish(specs []PublishModelSpec) (*PublishResponse, error) {
 	return &result, nil
 }
 
+// publishTimeout allows for each model's startup health checks (including
+// retries), connecting to the hub, and waiting for provider IDs.
+func publishTimeout(specs []PublishModelSpec) time.Duration {
+	timeout := 30 * time.Second
+	for _, s := range specs {
+		timeout += provider.HealthRetryBudget(s.HealthRetries, s.HealthRetryInterval)
+	}
+	return timeout
+}
+
 // Reauth tells the daemon that credentials have changed. The daemon stops all
 // running bridges so the next publish uses the new user's tokens.
 func (c *Client) Reauth() error {
@@ -177,6 +198,37 @@ func (c *Client) Reauth() error {
 	return nil
 }
 
+// Pause asks the daemon to stop routing new requests to the given models
+// while their in-flight requests finish. No names pauses every model.
+func (c *Client) Pause(modelNames []string) (*PublishResponse, error) {
+	return c.setPaused("pause", modelNames)
+}
+
+// Resume undoes Pause. No names resumes every model.
+func (c *Client) Resume(modelNames []string) (*PublishResponse, error) {
+	return c.setPaused("resume", modelNames)
+}
+
+func (c *Client) setPaused(action string, modelNames []string) (*PublishResponse, error) {
+	body, _ := json.Marshal(PauseRequest{Models: modelNames})
+	resp, err := c.doRequest("POST", "http://daemon/api/"+action, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s failed: %s", action, string(data))
+	}
+
+	var result PublishResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &result, nil
+}
+
 // Unpublish requests the daemon to unpublish one or more models.
 func (c *Client) Unpublish(modelNames []string) (*PublishResponse, error) {
 	body, _ := json.Marshal(UnpublishRequest{Models: modelNames})
@@ -20,6 +20,8 @@ import (
 	"time"
 
 	"github.com/cllmhub/cllmhub-cli/internal/auth"
+	"github.com/cllmhub/cllmhub-cli/internal/hub"
+	"github.com/cllmhub/cllmhub-cli/internal/provider"
 )
 
 // StatusResponse is returned by GET /api/status.
@@ -32,10 +34,13 @@ type StatusResponse struct {
 // ModelStatus represents the state of a single model.
 type ModelStatus struct {
 	Name          string `json:"name"`
-	State         string `json:"state"`         // "published", "error"
+	State         string `json:"state"`         // "published", "paused", "error"
 	Backend       string `json:"backend"`       // "ollama", "vllm", "lmstudio", "mlx", "llamacpp"
 	ProviderID    string `json:"provider_id"`   // cLLMHub provider ID
 	MaxConcurrent int    `json:"max_concurrent"` // concurrent request slots
+
+	RequestLatencyMs  *provider.HistogramSnapshot `json:"request_latency_ms,omitempty"`
+	HeartbeatFailures int64                       `json:"heartbeat_failures,omitempty"` // failed heartbeat sends
 }
 
 // PublishRequest is the body for POST /api/publish.
@@ -51,6 +56,37 @@ type PublishModelSpec struct {
 	BackendAPIKey string `json:"backend_api_key,omitempty"`
 	Description   string `json:"description,omitempty"`
 	MaxConcurrent int    `json:"max_concurrent,omitempty"`  // optional ceiling hint for concurrent slots
+	OllamaContext bool   `json:"ollama_context,omitempty"`  // reuse Ollama context across a session's turns
+
+	MaxTokensLimit  int    `json:"max_tokens_limit,omitempty"`  // clamp for requested max_tokens; 0 = none
+	OllamaKeepAlive string `json:"ollama_keep_alive,omitempty"` // Ollama keep_alive, e.g. "-1" or "30m"
+
+	RequestTimeout time.Duration `json:"request_timeout,omitempty"` // per-request backend deadline; 0 = none
+
+	HealthRetries       int           `json:"health_retries,omitempty"`        // extra startup health checks; 0 = check once
+	HealthRetryInterval time.Duration `json:"health_retry_interval,omitempty"` // first retry delay, doubling; 0 = 2s
+
+	LlamaCppChat bool `json:"llamacpp_chat,omitempty"` // send prompts to llama.cpp's chat endpoint
+
+	MaxQueue int `json:"max_queue,omitempty"` // requests allowed to wait for a slot; 0 = no limit
+
+	ProviderID string `json:"provider_id,omitempty"` // register under this ID; empty = stable ID derived for the model
+
+	CompletionsPath string `json:"completions_path,omitempty"` // backend prompt endpoint override
+	ChatPath        string `json:"chat_path,omitempty"`        // backend chat endpoint override
+	InfillPath      string `json:"infill_path,omitempty"`      // llama.cpp /infill endpoint override
+
+	StreamCoalesce time.Duration `json:"stream_coalesce,omitempty"` // batch stream tokens for this long; 0 = off
+
+	BackendModel string `json:"backend_model,omitempty"` // model name sent to the backend; empty = Name
+}
+
+// ServedModel returns the model name the backend serves the model under.
+func (s PublishModelSpec) ServedModel() string {
+	if s.BackendModel != "" {
+		return s.BackendModel
+	}
+	return s.Name
 }
 
 // UnpublishRequest is the body for POST /api/unpublish.
@@ -58,6 +94,12 @@ type UnpublishRequest struct {
 	Models []string `json:"models"`
 }
 
+// PauseRequest is the body for POST /api/pause and POST /api/resume.
+// An empty Models list applies to every published model.
+type PauseRequest struct {
+	Models []string `json:"models"`
+}
+
 // PublishResponse is the response for POST /api/publish.
 type PublishResponse struct {
 	Results []PublishResult `json:"results"`
@@ -74,7 +116,11 @@ type PublishResult struct {
 
 // Options holds configuration for the daemon.
 type Options struct {
-	Watch bool // Proactively watch backend health and unpublish unreachable models
+	Watch         bool          // Proactively watch backend health and unpublish unreachable models
+	WatchInterval time.Duration // Health check period with Watch; 0 = default (30s)
+	LogFormat     string        // "json" (default) or "text"
+
+	HealthAddr string // TCP address for /healthz and /readyz probes; empty = disabled
 }
 
 // Daemon is the background process that manages bridge services.
@@ -85,6 +131,10 @@ type Daemon struct {
 	logFile   *os.File
 	watch     bool
 
+	watchInterval time.Duration
+	logFormat     string
+	healthAddr    string
+
 	bridges *BridgeManager
 
 	authToken string
@@ -102,21 +152,28 @@ func New(opts Options) *Daemon {
 		ctx:    ctx,
 		cancel: cancel,
 		watch:  opts.Watch,
+
+		watchInterval: opts.WatchInterval,
+		logFormat:     opts.LogFormat,
+		healthAddr:    opts.HealthAddr,
 	}
 }
 
 // Run starts the daemon. It blocks until shutdown.
 func (d *Daemon) Run() error {
-	logger, logFile, err := NewLogger()
+	logger, logFile, err := NewLogger(d.logFormat)
 	if err != nil {
 		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
+	// Route the standard log package (hub and auth messages) through the same
+	// handler so the whole file is in one format.
+	slog.SetDefault(logger)
 	d.logger = logger
 	d.logFile = logFile
 	defer logFile.Close()
 
 	d.startTime = time.Now()
-	d.bridges = NewBridgeManager(logger, d.watch)
+	d.bridges = NewBridgeManager(logger, d.watch, d.watchInterval)
 
 	// Generate and write auth token
 	if err := d.writeAuthToken(); err != nil {
@@ -159,12 +216,25 @@ func (d *Daemon) Run() error {
 	d.registerRoutes(mux)
 	d.server = &http.Server{Handler: d.authMiddleware(mux)}
 
+	if d.healthAddr != "" {
+		healthServer, err := d.serveHealth(d.healthAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on health address: %w", err)
+		}
+		defer healthServer.Close()
+	}
+
 	d.logger.Info("daemon started", "pid", os.Getpid(), "socket", sockPath)
 
 	// Handle signals
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	pauseCh := make(chan os.Signal, 1)
+	if len(pauseSignals) > 0 {
+		signal.Notify(pauseCh, pauseSignals...)
+	}
+
 	// Start HTTP server in background
 	serverErr := make(chan error, 1)
 	go func() {
@@ -175,16 +245,25 @@ func (d *Daemon) Run() error {
 	}()
 
 	// Wait for shutdown signal or server error
-	select {
-	case <-sigCh:
-		d.logger.Info("received shutdown signal")
-	case err := <-serverErr:
-		if err != nil {
-			d.logger.Error("server error", "error", err)
-			return err
+wait:
+	for {
+		select {
+		case <-pauseCh:
+			paused := d.bridges.TogglePaused()
+			d.logger.Info("received pause signal", "paused", paused)
+		case <-sigCh:
+			d.logger.Info("received shutdown signal")
+			break wait
+		case err := <-serverErr:
+			if err != nil {
+				d.logger.Error("server error", "error", err)
+				return err
+			}
+			break wait
+		case <-d.ctx.Done():
+			d.logger.Info("shutdown requested via API")
+			break wait
 		}
-	case <-d.ctx.Done():
-		d.logger.Info("shutdown requested via API")
 	}
 
 	d.shutdown()
@@ -198,6 +277,8 @@ func (d *Daemon) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/publish", d.handlePublish)
 	mux.HandleFunc("POST /api/unpublish", d.handleUnpublish)
 	mux.HandleFunc("POST /api/reauth", d.handleReauth)
+	mux.HandleFunc("POST /api/pause", d.handlePause(true))
+	mux.HandleFunc("POST /api/resume", d.handlePause(false))
 }
 
 func (d *Daemon) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -214,12 +295,19 @@ func (d *Daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
 
 	// Add published models with backend info
 	for _, info := range d.bridges.PublishedModelsWithBackend() {
+		state := "published"
+		if info.Paused {
+			state = "paused"
+		}
 		resp.Models = append(resp.Models, ModelStatus{
 			Name:          info.Name,
-			State:         "published",
+			State:         state,
 			Backend:       info.Backend,
 			ProviderID:    info.ProviderID,
 			MaxConcurrent: info.MaxConcurrent,
+
+			RequestLatencyMs:  info.RequestLatencyMs,
+			HeartbeatFailures: info.HeartbeatFailures,
 		})
 	}
 
@@ -334,11 +422,42 @@ func (d *Daemon) handleUnpublish(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// handlePause returns the handler for POST /api/pause (paused=true) and
+// POST /api/resume (paused=false).
+func (d *Daemon) handlePause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PauseRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBodySize)).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		models := req.Models
+		if len(models) == 0 {
+			models = d.bridges.PublishedModels()
+		}
+
+		resp := PublishResponse{Results: make([]PublishResult, 0, len(models))}
+		for _, name := range models {
+			result := PublishResult{Model: name}
+			if err := d.bridges.SetPaused(name, paused); err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Success = true
+			}
+			resp.Results = append(resp.Results, result)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
 func (d *Daemon) handleReauth(w http.ResponseWriter, r *http.Request) {
 	published := d.bridges.PublishedModels()
 	if len(published) > 0 {
 		d.logger.Info("reauth: stopping all bridges for credential refresh", "models", published)
-		d.bridges.StopAll()
+		d.bridges.stopAll(hub.UnregisterReauth)
 	}
 
 	d.logger.Info("reauth: credentials refreshed, ready for new publishes")
@@ -1,14 +1,21 @@
 package daemon
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+
+	"github.com/cllmhub/cllmhub-cli/internal/provider"
 )
 
 func TestNewBridgeManager(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	bm := NewBridgeManager(logger, false)
+	bm := NewBridgeManager(logger, false, 0)
 
 	if bm.Count() != 0 {
 		t.Errorf("Count = %d, want 0", bm.Count())
@@ -20,7 +27,7 @@ func TestNewBridgeManager(t *testing.T) {
 
 func TestBridgeManager_IsPublished(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	bm := NewBridgeManager(logger, false)
+	bm := NewBridgeManager(logger, false, 0)
 
 	if bm.IsPublished("any-model") {
 		t.Error("expected false for unpublished model")
@@ -29,7 +36,7 @@ func TestBridgeManager_IsPublished(t *testing.T) {
 
 func TestBridgeManager_StopBridge_NotPublished(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	bm := NewBridgeManager(logger, false)
+	bm := NewBridgeManager(logger, false, 0)
 
 	err := bm.StopBridge("missing")
 	if err == nil {
@@ -39,7 +46,7 @@ func TestBridgeManager_StopBridge_NotPublished(t *testing.T) {
 
 func TestBridgeManager_StopAll_Empty(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	bm := NewBridgeManager(logger, false)
+	bm := NewBridgeManager(logger, false, 0)
 
 	// Should not panic
 	bm.StopAll()
@@ -111,3 +118,73 @@ func TestRotateLog_NoFile(t *testing.T) {
 	// Should not panic
 	rotateLog("/nonexistent/path/test.log")
 }
+
+func TestCheckLogFormat(t *testing.T) {
+	for _, f := range []string{LogFormatText, LogFormatJSON} {
+		if err := CheckLogFormat(f); err != nil {
+			t.Errorf("CheckLogFormat(%q): %v", f, err)
+		}
+	}
+	if err := CheckLogFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	d := New(Options{})
+	d.logger = logger
+	d.bridges = NewBridgeManager(logger, false, 0)
+
+	probe := func(path string) (int, healthResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		if path == "/healthz" {
+			d.handleHealthz(rec, req)
+		} else {
+			d.handleReadyz(rec, req)
+		}
+		var body healthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: decode body: %v", path, err)
+		}
+		return rec.Code, body
+	}
+	check := func(stage string, wantLive, wantReady int) {
+		t.Helper()
+		if code, _ := probe("/healthz"); code != wantLive {
+			t.Errorf("%s: /healthz = %d, want %d", stage, code, wantLive)
+		}
+		if code, _ := probe("/readyz"); code != wantReady {
+			t.Errorf("%s: /readyz = %d, want %d", stage, code, wantReady)
+		}
+	}
+
+	check("no models", http.StatusOK, http.StatusServiceUnavailable)
+
+	// A publish in progress is alive but not ready.
+	d.bridges.bridges["starting"] = &Bridge{model: "starting", done: make(chan struct{})}
+	check("starting", http.StatusOK, http.StatusServiceUnavailable)
+
+	conn := &connState{}
+	d.bridges.bridges["lost"] = &Bridge{model: "lost", provider: &provider.Provider{}, conn: conn}
+	conn.OnConnected("p1")
+	conn.OnDisconnected(errors.New("connection lost"))
+	check("disconnected", http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	if _, body := probe("/healthz"); body.Models["lost"] != healthDisconnected || body.Models["starting"] != healthStarting {
+		t.Errorf("models = %v", body.Models)
+	}
+}
+
+func TestPublishModelSpec_ServedModel(t *testing.T) {
+	spec := PublishModelSpec{Name: "llama3-70b"}
+	if got := spec.ServedModel(); got != "llama3-70b" {
+		t.Errorf("ServedModel() = %q, want the published name", got)
+	}
+	spec.BackendModel = "meta-llama-3-70b-instruct"
+	if got := spec.ServedModel(); got != "meta-llama-3-70b-instruct" {
+		t.Errorf("ServedModel() = %q, want the backend model", got)
+	}
+}
@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/cllmhub/cllmhub-cli/internal/provider"
+)
+
+// connState tracks whether a bridge's model is currently registered on the
+// hub, from the provider's lifecycle events. The provider reports a backend
+// outage as a disconnect, since it unpublishes the model.
+type connState struct {
+	provider.NopEventHandler
+	connected atomic.Bool
+}
+
+func (s *connState) OnConnected(providerID string) { s.connected.Store(true) }
+func (s *connState) OnDisconnected(err error)      { s.connected.Store(false) }
+
+// Model health values reported by /healthz and /readyz.
+const (
+	healthOK           = "ok"
+	healthStarting     = "starting"     // publish in progress, not yet registered
+	healthDisconnected = "disconnected" // hub connection lost or backend down
+	healthDegraded     = "degraded"     // connected, but failing backend health checks
+)
+
+// healthResponse is the body of /healthz and /readyz.
+type healthResponse struct {
+	Status string            `json:"status"`           // "ok" or "unavailable"
+	Models map[string]string `json:"models,omitempty"` // per-model health
+}
+
+// serveHealth serves unauthenticated liveness and readiness probes on a TCP
+// address, for orchestrators such as Kubernetes:
+//
+//   - GET /healthz is 200 while every published model is connected to the
+//     hub with a healthy backend, 503 otherwise. With no models published
+//     the daemon itself is alive, so it is 200.
+//   - GET /readyz is 200 once at least one model is published and every
+//     published model has registered with the hub, 503 otherwise.
+func (d *Daemon) serveHealth(addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", d.handleHealthz)
+	mux.HandleFunc("GET /readyz", d.handleReadyz)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			d.logger.Error("health server error", "error", err)
+		}
+	}()
+	d.logger.Info("health endpoint listening", "addr", listener.Addr().String())
+	return server, nil
+}
+
+func (d *Daemon) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	models := d.bridges.Health()
+	ok := true
+	for _, h := range models {
+		// A model still registering has not failed yet.
+		if h != healthOK && h != healthStarting {
+			ok = false
+		}
+	}
+	writeHealth(w, ok, models)
+}
+
+func (d *Daemon) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	models := d.bridges.Health()
+	ok := len(models) > 0
+	for _, h := range models {
+		// Degraded models are still registered and reachable.
+		if h != healthOK && h != healthDegraded {
+			ok = false
+		}
+	}
+	writeHealth(w, ok, models)
+}
+
+func writeHealth(w http.ResponseWriter, ok bool, models map[string]string) {
+	resp := healthResponse{Status: "ok", Models: models}
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		resp.Status = "unavailable"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -14,9 +14,32 @@ const (
 	maxLogBackups = 3
 )
 
-// NewLogger creates a structured JSON logger writing to ~/.cllmhub/logs/daemon.log.
+// Log formats accepted by NewLogger.
+const (
+	LogFormatJSON = "json" // one JSON object per line, for systemd/k8s log collectors
+	LogFormatText = "text" // key=value lines, easy to read with `cllmhub logs`
+)
+
+// CheckLogFormat returns an error if format is not a supported log format.
+func CheckLogFormat(format string) error {
+	switch format {
+	case LogFormatText, LogFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown log format %q: use %q or %q", format, LogFormatText, LogFormatJSON)
+}
+
+// NewLogger creates a structured logger writing to ~/.cllmhub/logs/daemon.log
+// in the given format ("" means JSON, the format daemon.log has always used).
 // It returns the logger and the underlying file (caller must close).
-func NewLogger() (*slog.Logger, *os.File, error) {
+func NewLogger(format string) (*slog.Logger, *os.File, error) {
+	if format == "" {
+		format = LogFormatJSON
+	}
+	if err := CheckLogFormat(format); err != nil {
+		return nil, nil, err
+	}
+
 	logDir, err := paths.LogDir()
 	if err != nil {
 		return nil, nil, err
@@ -32,9 +55,13 @@ func NewLogger() (*slog.Logger, *os.File, error) {
 		return nil, nil, fmt.Errorf("cannot open log file: %w", err)
 	}
 
-	handler := slog.NewJSONHandler(f, &slog.HandlerOptions{
+	opts := &slog.HandlerOptions{
 		Level: slog.LevelInfo,
-	})
+	}
+	var handler slog.Handler = slog.NewTextHandler(f, opts)
+	if format == LogFormatJSON {
+		handler = slog.NewJSONHandler(f, opts)
+	}
 	return slog.New(handler), f, nil
 }
 
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+)
+
+// pauseSignals toggle pause on every published model.
+var pauseSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package daemon
+
+import "os"
+
+// pauseSignals is empty on Windows, which has no SIGUSR1; use
+// 'cllmhub pause' instead.
+var pauseSignals []os.Signal
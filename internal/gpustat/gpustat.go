@@ -0,0 +1,153 @@
+// Package gpustat samples GPU utilization for provider heartbeats.
+//
+// Sampling is best-effort: it shells out to nvidia-smi, looking beyond PATH
+// for the locations the NVIDIA container toolkit mounts it at, and reports
+// nothing when no NVIDIA GPU is visible. Failures are never fatal.
+package gpustat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nvidiaSMIPaths are tried in order. Containers started with the NVIDIA
+// runtime often have nvidia-smi mounted outside PATH.
+var nvidiaSMIPaths = []string{
+	"nvidia-smi",
+	"/usr/bin/nvidia-smi",
+	"/usr/local/nvidia/bin/nvidia-smi",
+	"/usr/local/bin/nvidia-smi",
+}
+
+const sampleTimeout = 5 * time.Second
+
+// ErrUnavailable is returned when no GPU utilization can be read.
+var ErrUnavailable = errors.New("gpu utilization unavailable")
+
+// GPU is the utilization of one device.
+type GPU struct {
+	Index int     `json:"index"`
+	Util  float64 `json:"util"` // percent, 0-100
+}
+
+// Stats is one utilization sample across all visible GPUs.
+type Stats struct {
+	GPUs    []GPU   `json:"gpus"`
+	Average float64 `json:"average"` // mean of GPUs[].Util, percent
+}
+
+// Sample reads current utilization from nvidia-smi.
+func Sample(ctx context.Context) (Stats, error) {
+	bin := findNvidiaSMI()
+	if bin == "" {
+		return Stats{}, ErrUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sampleTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "--query-gpu=index,utilization.gpu", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return Stats{}, ErrUnavailable
+	}
+	return parseNvidiaSMI(out)
+}
+
+func findNvidiaSMI() string {
+	for _, p := range nvidiaSMIPaths {
+		if path, err := exec.LookPath(p); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// parseNvidiaSMI parses "index, utilization" CSV lines. Devices that do
+// not report utilization, such as MIG-partitioned GPUs ("[N/A]"), are
+// skipped rather than counted as idle.
+func parseNvidiaSMI(out []byte) (Stats, error) {
+	var stats Stats
+	var total float64
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) != 2 {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			continue
+		}
+		util, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			continue
+		}
+		stats.GPUs = append(stats.GPUs, GPU{Index: index, Util: util})
+		total += util
+	}
+
+	if len(stats.GPUs) == 0 {
+		return Stats{}, ErrUnavailable
+	}
+	stats.Average = total / float64(len(stats.GPUs))
+	return stats, nil
+}
+
+// Sampler caches samples so callers on hot paths (heartbeats) never wait
+// on nvidia-smi: Utilization returns the last sample immediately and
+// refreshes it in the background once it is older than the TTL.
+type Sampler struct {
+	ttl    time.Duration
+	sample func(context.Context) (Stats, error)
+
+	mu         sync.Mutex
+	stats      Stats
+	sampledAt  time.Time
+	refreshing bool
+}
+
+// NewSampler creates a sampler that resamples at most once per ttl.
+func NewSampler(ttl time.Duration) *Sampler {
+	return &Sampler{ttl: ttl, sample: Sample}
+}
+
+// Utilization returns the average utilization (percent) from the latest
+// sample, or 0 if none is available. It is safe on a nil Sampler.
+func (s *Sampler) Utilization() float64 {
+	return s.Stats().Average
+}
+
+// Stats returns the latest sample. It is safe on a nil Sampler.
+func (s *Sampler) Stats() Stats {
+	if s == nil {
+		return Stats{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.sampledAt) >= s.ttl && !s.refreshing {
+		s.refreshing = true
+		go s.refresh()
+	}
+	return s.stats
+}
+
+func (s *Sampler) refresh() {
+	stats, err := s.sample(context.Background())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		stats = Stats{}
+	}
+	s.stats = stats
+	s.sampledAt = time.Now()
+	s.refreshing = false
+}
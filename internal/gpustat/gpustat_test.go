@@ -0,0 +1,70 @@
+package gpustat
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseNvidiaSMI(t *testing.T) {
+	out := []byte("0, 40\n1, 80\n")
+	stats, err := parseNvidiaSMI(out)
+	if err != nil {
+		t.Fatalf("parseNvidiaSMI: %v", err)
+	}
+	if len(stats.GPUs) != 2 || stats.GPUs[1].Index != 1 || stats.GPUs[1].Util != 80 {
+		t.Errorf("GPUs = %+v", stats.GPUs)
+	}
+	if stats.Average != 60 {
+		t.Errorf("Average = %v, want 60", stats.Average)
+	}
+}
+
+func TestParseNvidiaSMI_SkipsMIG(t *testing.T) {
+	out := []byte("0, [N/A]\n1, 30\n")
+	stats, err := parseNvidiaSMI(out)
+	if err != nil {
+		t.Fatalf("parseNvidiaSMI: %v", err)
+	}
+	if len(stats.GPUs) != 1 || stats.Average != 30 {
+		t.Errorf("stats = %+v, want only GPU 1 at 30%%", stats)
+	}
+}
+
+func TestParseNvidiaSMI_NoGPUs(t *testing.T) {
+	if _, err := parseNvidiaSMI([]byte("0, [N/A]\n")); err != ErrUnavailable {
+		t.Errorf("err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestSampler_RefreshesInBackground(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	s := &Sampler{ttl: time.Hour, sample: func(context.Context) (Stats, error) {
+		calls <- struct{}{}
+		return Stats{Average: 42}, nil
+	}}
+
+	// The first call returns immediately with no data and starts a refresh.
+	if got := s.Utilization(); got != 0 {
+		t.Errorf("first Utilization = %v, want 0", got)
+	}
+	<-calls
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Utilization() != 42 {
+		if time.Now().After(deadline) {
+			t.Fatal("sample never became visible")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(calls) != 0 {
+		t.Errorf("resampled %d extra times within the TTL", len(calls))
+	}
+}
+
+func TestSampler_Nil(t *testing.T) {
+	var s *Sampler
+	if got := s.Utilization(); got != 0 {
+		t.Errorf("nil Sampler Utilization = %v, want 0", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -59,6 +60,23 @@ const (
 	MsgTypePing        = "ping"
 )
 
+// Provider statuses reported in heartbeats. A degraded or paused provider
+// stays connected but asks the gateway to stop routing new requests to it.
+const (
+	StatusOnline   = "online"
+	StatusDegraded = "degraded"
+	StatusPaused   = "paused"
+)
+
+// Reasons sent with an unregister message, so the gateway can tell a clean
+// shutdown from a crash and why the provider left.
+const (
+	UnregisterShutdown    = "shutdown"     // the CLI or daemon is exiting
+	UnregisterUnpublish   = "unpublish"    // the user unpublished the model
+	UnregisterReauth      = "reauth"       // the user logged in again
+	UnregisterBackendDown = "backend_down" // the backend stayed unreachable
+)
+
 // Envelope is used to peek at the message type.
 type Envelope struct {
 	Type string `json:"type"`
@@ -72,16 +90,47 @@ type RequestMsg struct {
 	Prompt    string              `json:"prompt"`
 	Messages  json.RawMessage     `json:"messages,omitempty"`
 	Params    InferenceParams     `json:"params"`
+	SessionID string              `json:"session_id,omitempty"` // set by the gateway for multi-turn conversations
+}
+
+// RegisteredMsg is the gateway's confirmation of a register message. Fields
+// the gateway does not send are left zero.
+type RegisteredMsg struct {
+	Type          string   `json:"type"`
+	ProviderID    string   `json:"provider_id,omitempty"`    // gateway-assigned ID, if any
+	ServerTime    string   `json:"server_time,omitempty"`    // gateway clock, as sent
+	MaxConcurrent int      `json:"max_concurrent,omitempty"` // slot count the gateway accepted
+	Price         *float64 `json:"price,omitempty"`          // price the gateway accepted
 }
 
 // InferenceParams mirrors the gateway params.
 type InferenceParams struct {
-	MaxTokens   int     `json:"max_tokens,omitempty"`
-	Temperature float64 `json:"temperature,omitempty"`
-	TopP        float64 `json:"top_p,omitempty"`
-	Stream      bool    `json:"stream,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature float64  `json:"temperature,omitempty"`
+	TopP        float64  `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	LogProbs    *bool    `json:"logprobs,omitempty"`
+	TopLogProbs *int     `json:"top_logprobs,omitempty"`
+	Stream      bool     `json:"stream,omitempty"`
+
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"` // OpenAI-style, e.g. {"type":"json_object"}
+	Grammar        string          `json:"grammar,omitempty"`         // GBNF grammar (llama.cpp)
+
+	Prefix string `json:"prefix,omitempty"` // fill-in-the-middle: text before the cursor
+	Suffix string `json:"suffix,omitempty"` // fill-in-the-middle: text after the cursor
+
+	Priority string `json:"priority,omitempty"` // PriorityHigh, PriorityNormal (default), or PriorityLow
 }
 
+// Request priorities. When requests queue for a slot, higher priorities
+// are served first.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
 // Usage contains token usage information.
 type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
@@ -103,10 +152,16 @@ type HubClient struct {
 	token         string
 	tokenFunc     TokenFunc
 
-	ws   *websocket.Conn
-	wsMu sync.Mutex
+	ws     *websocket.Conn
+	wsMu   sync.Mutex
+	closed bool // set by Close/Disconnect; guarded by wsMu
+
+	registered RegisteredMsg
 }
 
+// ErrClosed is returned when sending on a connection that has been closed.
+var ErrClosed = errors.New("hub connection closed")
+
 // ConnectConfig holds parameters for connecting to the hub.
 type ConnectConfig struct {
 	HubURL        string
@@ -116,11 +171,20 @@ type ConnectConfig struct {
 	Description   string
 	MaxConcurrent int
 	Token         string
+
+	// BackendModel is the model name the backend actually serves when it
+	// differs from Model (e.g. Ollama resolving "llama3" to "llama3:latest",
+	// or a name given with --backend-model).
+	BackendModel string
 }
 
 // Connect dials the gateway WebSocket, sends a register message, and waits for confirmation.
 func Connect(cfg ConnectConfig) (*HubClient, error) {
-	u, err := url.Parse(cfg.HubURL)
+	hubURL, err := NormalizeURL(cfg.HubURL)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(hubURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid hub URL: %w", err)
 	}
@@ -131,10 +195,6 @@ func Connect(cfg ConnectConfig) (*HubClient, error) {
 		u.Scheme = "ws"
 	case "https":
 		u.Scheme = "wss"
-	case "ws", "wss":
-		// already correct
-	default:
-		u.Scheme = "ws"
 	}
 	u.Path = "/provider/ws"
 
@@ -149,7 +209,7 @@ func Connect(cfg ConnectConfig) (*HubClient, error) {
 	}
 
 	c := &HubClient{
-		hubURL:        cfg.HubURL,
+		hubURL:        hubURL,
 		providerID:    cfg.ProviderID,
 		model:         cfg.Model,
 		backend:       cfg.Backend,
@@ -170,6 +230,10 @@ func Connect(cfg ConnectConfig) (*HubClient, error) {
 		"max_concurrent": cfg.MaxConcurrent,
 		"token":          cfg.Token,
 	}
+	if cfg.BackendModel != "" {
+		reg["backend_model"] = cfg.BackendModel
+		log.Printf("[hub] Backend serves %s as %s", cfg.Model, cfg.BackendModel)
+	}
 
 	log.Printf("[hub] Sending register for provider=%s model=%s backend=%s", cfg.ProviderID, cfg.Model, cfg.Backend)
 	if err := c.writeJSON(reg); err != nil {
@@ -204,7 +268,29 @@ func Connect(cfg ConnectConfig) (*HubClient, error) {
 		return nil, fmt.Errorf("unexpected response type: %s", env.Type)
 	}
 
-	log.Printf("[hub] Registered provider=%s model=%s", cfg.ProviderID, cfg.Model)
+	if err := json.Unmarshal(raw, &c.registered); err != nil {
+		log.Printf("[hub] Ignoring malformed registered payload: %v", err)
+		c.registered = RegisteredMsg{Type: MsgTypeRegistered}
+	}
+	ack := c.registered
+
+	// The gateway may assign its own provider ID; when it does, that ID
+	// replaces the locally generated one.
+	if ack.ProviderID != "" && ack.ProviderID != cfg.ProviderID {
+		log.Printf("[hub] Gateway assigned provider ID %s (requested %s)", ack.ProviderID, cfg.ProviderID)
+		c.providerID = ack.ProviderID
+	}
+
+	log.Printf("[hub] Registered provider=%s model=%s", c.providerID, cfg.Model)
+	if ack.MaxConcurrent != 0 && ack.MaxConcurrent != cfg.MaxConcurrent {
+		log.Printf("[hub] Gateway accepted max_concurrent=%d (requested %d)", ack.MaxConcurrent, cfg.MaxConcurrent)
+	}
+	if ack.Price != nil {
+		log.Printf("[hub] Gateway accepted price=%g", *ack.Price)
+	}
+	if ack.ServerTime != "" {
+		log.Printf("[hub] Gateway server time %s", ack.ServerTime)
+	}
 
 	// Limit inbound WebSocket messages to 16MB to prevent memory exhaustion.
 	ws.SetReadLimit(16 * 1024 * 1024)
@@ -212,8 +298,22 @@ func Connect(cfg ConnectConfig) (*HubClient, error) {
 	return c, nil
 }
 
+// ProviderID returns the provider ID the gateway registered this connection
+// under: the gateway-assigned ID if it sent one, else ConnectConfig.ProviderID.
+func (c *HubClient) ProviderID() string {
+	return c.providerID
+}
+
+// Registered returns the gateway's registration confirmation for this
+// connection.
+func (c *HubClient) Registered() RegisteredMsg {
+	return c.registered
+}
+
 // ReadLoop reads messages from the WebSocket and dispatches requests to the callback.
 // It blocks until the context is cancelled or the connection is closed.
+// onRequest is called on the read goroutine and must not block; callers
+// are expected to hand the request off to their own goroutine.
 func (c *HubClient) ReadLoop(ctx context.Context, onRequest func(req RequestMsg), onPing func()) error {
 	done := make(chan struct{})
 	go func() {
@@ -245,7 +345,7 @@ func (c *HubClient) ReadLoop(ctx context.Context, onRequest func(req RequestMsg)
 				log.Printf("invalid request message: %v", err)
 				continue
 			}
-			go onRequest(req)
+			onRequest(req)
 		case MsgTypePing:
 			if onPing != nil {
 				onPing()
@@ -255,7 +355,8 @@ func (c *HubClient) ReadLoop(ctx context.Context, onRequest func(req RequestMsg)
 }
 
 // SendResponse sends a non-streaming response back to the gateway.
-func (c *HubClient) SendResponse(requestID, text, providerID string, latencyMs int64, usage Usage) error {
+// logprobs is the backend's OpenAI-format logprobs object, omitted when nil.
+func (c *HubClient) SendResponse(requestID, text, providerID string, latencyMs int64, usage Usage, logprobs json.RawMessage) error {
 	msg := map[string]interface{}{
 		"type":        MsgTypeResponse,
 		"request_id":  requestID,
@@ -264,6 +365,9 @@ func (c *HubClient) SendResponse(requestID, text, providerID string, latencyMs i
 		"latency_ms":  latencyMs,
 		"usage":       usage,
 	}
+	if len(logprobs) > 0 {
+		msg["logprobs"] = logprobs
+	}
 	return c.writeJSON(msg)
 }
 
@@ -309,18 +413,20 @@ func (c *HubClient) UpdateMaxConcurrent(maxConcurrent int) error {
 
 // SendHeartbeat sends a heartbeat to the gateway.
 func (c *HubClient) SendHeartbeat(queueDepth int, gpuUtil float64) error {
-	return c.SendHeartbeatWithToken(queueDepth, gpuUtil, "")
+	return c.SendHeartbeatWithToken(queueDepth, gpuUtil, "", StatusOnline)
 }
 
 // SendHeartbeatWithToken sends a heartbeat that includes a fresh access token.
 // When token is non-empty, the gateway uses it to update the session credential.
-func (c *HubClient) SendHeartbeatWithToken(queueDepth int, gpuUtil float64, token string) error {
+// status is StatusOnline, StatusDegraded, or StatusPaused.
+func (c *HubClient) SendHeartbeatWithToken(queueDepth int, gpuUtil float64, token, status string) error {
 	msg := map[string]interface{}{
 		"type":        MsgTypeHeartbeat,
 		"provider_id": c.providerID,
 		"model":       c.model,
 		"queue_depth": queueDepth,
 		"gpu_util":    gpuUtil,
+		"status":      status,
 	}
 	if token != "" {
 		msg["token"] = token
@@ -389,12 +495,21 @@ func (c *HubClient) currentToken() string {
 // SendUnpublish sends an unregister message over the WebSocket so the hub
 // removes the provider immediately, before the connection is closed.
 func (c *HubClient) SendUnpublish() error {
-	log.Printf("[hub] Sending unregister for provider=%s model=%s", c.providerID, c.model)
+	return c.SendUnpublishWithReason("")
+}
+
+// SendUnpublishWithReason sends an unregister message that says why the
+// provider is leaving, e.g. UnregisterShutdown. An empty reason is omitted.
+func (c *HubClient) SendUnpublishWithReason(reason string) error {
+	log.Printf("[hub] Sending unregister for provider=%s model=%s reason=%s", c.providerID, c.model, reason)
 	msg := map[string]interface{}{
 		"type":        MsgTypeUnregister,
 		"provider_id": c.providerID,
 		"model":       c.model,
 	}
+	if reason != "" {
+		msg["reason"] = reason
+	}
 	return c.writeJSON(msg)
 }
 
@@ -406,9 +521,12 @@ func (c *HubClient) Disconnect() {
 	}
 	log.Printf("[hub] Disconnecting provider=%s model=%s", c.providerID, c.model)
 	c.wsMu.Lock()
-	c.ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	c.ws.WriteMessage(websocket.CloseMessage,
-		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "provider disconnecting"))
+	if !c.closed {
+		c.ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		c.ws.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "provider disconnecting"))
+		c.closed = true
+	}
 	c.wsMu.Unlock()
 	c.ws.Close()
 	log.Printf("[hub] Disconnected provider=%s model=%s", c.providerID, c.model)
@@ -416,14 +534,23 @@ func (c *HubClient) Disconnect() {
 
 // Close closes the WebSocket connection without a close handshake.
 func (c *HubClient) Close() {
+	c.wsMu.Lock()
+	c.closed = true
+	c.wsMu.Unlock()
 	if c.ws != nil {
 		c.ws.Close()
 	}
 }
 
+// writeJSON sends v on the WebSocket. Once the connection has been closed
+// it returns ErrClosed, so handlers still finishing after shutdown or an
+// unpublish get an error instead of writing to a dead connection.
 func (c *HubClient) writeJSON(v interface{}) error {
 	c.wsMu.Lock()
 	defer c.wsMu.Unlock()
+	if c.closed || c.ws == nil {
+		return ErrClosed
+	}
 	c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
 	return c.ws.WriteJSON(v)
 }
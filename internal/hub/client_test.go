@@ -0,0 +1,62 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialTestServer returns a HubClient connected to a server that decodes
+// every message it receives onto the returned channel.
+func dialTestServer(t *testing.T) (*HubClient, <-chan map[string]any) {
+	t.Helper()
+	msgs := make(chan map[string]any, 10)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			var m map[string]any
+			if err := ws.ReadJSON(&m); err != nil {
+				return
+			}
+			msgs <- m
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	c := &HubClient{providerID: "p1", model: "m", ws: ws}
+	t.Cleanup(c.Close)
+	return c, msgs
+}
+
+func TestSendUnpublishWithReason(t *testing.T) {
+	c, msgs := dialTestServer(t)
+
+	if err := c.SendUnpublishWithReason(UnregisterUnpublish); err != nil {
+		t.Fatalf("SendUnpublishWithReason: %v", err)
+	}
+	m := <-msgs
+	if m["type"] != MsgTypeUnregister || m["reason"] != UnregisterUnpublish {
+		b, _ := json.Marshal(m)
+		t.Errorf("message = %s, want an unregister with reason %q", b, UnregisterUnpublish)
+	}
+
+	if err := c.SendUnpublish(); err != nil {
+		t.Fatalf("SendUnpublish: %v", err)
+	}
+	if m := <-msgs; m["reason"] != nil {
+		t.Errorf("reason = %v, want it omitted", m["reason"])
+	}
+}
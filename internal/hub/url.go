@@ -0,0 +1,40 @@
+package hub
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// NormalizeURL validates a hub URL and puts it in canonical form. A URL
+// without a scheme (e.g. "cllmhub.com") defaults to https, and a trailing
+// slash is dropped so callers can append API paths directly.
+func NormalizeURL(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", fmt.Errorf("hub URL is empty")
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid hub URL %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+	default:
+		return "", fmt.Errorf("invalid hub URL %q: unsupported scheme %q (use http or https)", raw, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid hub URL %q: missing host", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid hub URL %q: must not contain a query or fragment", raw)
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
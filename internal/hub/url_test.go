@@ -0,0 +1,43 @@
+package hub
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"cllmhub.com", "https://cllmhub.com"},
+		{"  cllmhub.com/  ", "https://cllmhub.com"},
+		{"localhost:8080", "https://localhost:8080"},
+		{"http://localhost:8080", "http://localhost:8080"},
+		{"https://cllmhub.com/", "https://cllmhub.com"},
+		{"https://example.com/hub/", "https://example.com/hub"},
+		{"wss://cllmhub.com", "wss://cllmhub.com"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeURL(tt.in)
+		if err != nil {
+			t.Errorf("NormalizeURL(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeURL_Invalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"ftp://cllmhub.com",
+		"https://",
+		"https://:8080",
+		"https://cllmhub.com?x=1",
+		"http://[::1",
+	} {
+		if got, err := NormalizeURL(in); err == nil {
+			t.Errorf("NormalizeURL(%q) = %q, want error", in, got)
+		}
+	}
+}
@@ -95,3 +95,13 @@ func DaemonTokenPath() (string, error) {
 	}
 	return filepath.Join(dir, "daemon.token"), nil
 }
+
+// ProviderIDPath returns the path to the file holding this install's
+// provider identity, from which stable per-model provider IDs are derived.
+func ProviderIDPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "provider_id"), nil
+}
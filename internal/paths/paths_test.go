@@ -145,6 +145,18 @@ func TestDaemonLogPath(t *testing.T) {
 	}
 }
 
+func TestProviderIDPath(t *testing.T) {
+	setupTestHome(t)
+
+	path, err := ProviderIDPath()
+	if err != nil {
+		t.Fatalf("ProviderIDPath: %v", err)
+	}
+	if !strings.HasSuffix(path, "provider_id") {
+		t.Errorf("ProviderIDPath = %q, want suffix provider_id", path)
+	}
+}
+
 func TestAllPathsUnderStateDir(t *testing.T) {
 	setupTestHome(t)
 
@@ -157,6 +169,7 @@ func TestAllPathsUnderStateDir(t *testing.T) {
 		ModelsDir,
 		BinDir,
 		DaemonLogPath,
+		ProviderIDPath,
 	}
 
 	for _, fn := range paths {
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamCoalescer batches streamed tokens so that backends emitting one
+// token per chunk (Ollama) don't cost one WebSocket message per token. The
+// first token added after a flush starts a timer; when it fires, everything
+// buffered since is sent as a single chunk.
+type streamCoalescer struct {
+	interval time.Duration
+	send     func(chunk string) error
+
+	mu    sync.Mutex
+	buf   strings.Builder
+	timer *time.Timer
+	err   error // first error from a timer-driven send
+}
+
+func newStreamCoalescer(interval time.Duration, send func(chunk string) error) *streamCoalescer {
+	return &streamCoalescer{interval: interval, send: send}
+}
+
+// add buffers token. It returns the error of an earlier timed send, so the
+// backend stream is aborted once the connection has failed.
+func (c *streamCoalescer) add(token string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.buf.WriteString(token)
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.interval, c.timedFlush)
+	}
+	return nil
+}
+
+func (c *streamCoalescer) timedFlush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timer = nil
+	if err := c.flushLocked(); err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+// flush sends whatever is buffered immediately and cancels the pending
+// timer. Nothing is sent after flush returns, so the caller may follow it
+// with the final done message.
+func (c *streamCoalescer) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.err != nil {
+		return c.err
+	}
+	return c.flushLocked()
+}
+
+func (c *streamCoalescer) flushLocked() error {
+	if c.buf.Len() == 0 {
+		return nil
+	}
+	chunk := c.buf.String()
+	c.buf.Reset()
+	return c.send(chunk)
+}
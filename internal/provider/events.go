@@ -0,0 +1,37 @@
+package provider
+
+// EventHandler receives provider lifecycle events, for supervisors that
+// embed a Provider and want to monitor it without parsing its log output.
+// Methods are called synchronously from the provider's goroutines and must
+// not block. Embed NopEventHandler to implement only some of them.
+type EventHandler interface {
+	// OnConnected is called when the model is published on the hub,
+	// including after a reconnect or a recovery from a backend outage.
+	OnConnected(providerID string)
+	// OnDisconnected is called when the model stops being published
+	// because the hub connection dropped or the backend went down.
+	OnDisconnected(err error)
+	// OnReconnect is called before each reconnect attempt (1-based).
+	OnReconnect(attempt int)
+	// OnRequest is called when a request arrives from the gateway.
+	OnRequest(requestID string)
+	// OnError is called when a request fails in the backend.
+	OnError(requestID string, err error)
+}
+
+// NopEventHandler ignores all events.
+type NopEventHandler struct{}
+
+func (NopEventHandler) OnConnected(providerID string)       {}
+func (NopEventHandler) OnDisconnected(err error)            {}
+func (NopEventHandler) OnReconnect(attempt int)             {}
+func (NopEventHandler) OnRequest(requestID string)          {}
+func (NopEventHandler) OnError(requestID string, err error) {}
+
+// events returns the configured event handler, or a no-op one.
+func (p *Provider) events() EventHandler {
+	if p.eventHandler == nil {
+		return NopEventHandler{}
+	}
+	return p.eventHandler
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cllmhub/cllmhub-cli/internal/paths"
+	"github.com/google/uuid"
+)
+
+var providerIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ValidateProviderID checks that id is 1-64 letters, digits, dots,
+// underscores, or hyphens.
+func ValidateProviderID(id string) error {
+	if !providerIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid provider ID %q: use 1-64 letters, digits, dots, underscores, or hyphens", id)
+	}
+	return nil
+}
+
+// newProviderID returns a random provider ID.
+func newProviderID() string {
+	return uuid.New().String()[:8]
+}
+
+// StableProviderID returns a provider ID for model that survives restarts,
+// so the gateway's dashboards keep its history. It is derived from an
+// install ID stored in ~/.cllmhub/provider_id (created on first use), so
+// each model published from this machine gets its own ID.
+func StableProviderID(model string) (string, error) {
+	path, err := paths.ProviderIDPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		data = []byte(newProviderID())
+		if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+			return "", fmt.Errorf("cannot save provider ID: %w", err)
+		}
+	default:
+		return "", fmt.Errorf("cannot read provider ID: %w", err)
+	}
+
+	installID := strings.TrimSpace(string(data))
+	if err := ValidateProviderID(installID); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return deriveProviderID(installID, model), nil
+}
+
+// deriveProviderID hashes the install ID and model name into an ID in the
+// same 8-hex-character form as a random one.
+func deriveProviderID(installID, model string) string {
+	sum := sha256.Sum256([]byte(installID + "\x00" + model))
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,72 @@
+package provider
+
+import "sync"
+
+// defaultLatencyBucketsMs are the upper bounds (in milliseconds) of the
+// request latency histogram, chosen to cover fast cached responses through
+// long generations.
+var defaultLatencyBucketsMs = []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 120000}
+
+// Histogram is a Prometheus-style cumulative histogram.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64 // upper bounds, ascending
+	counts  []uint64  // per-bucket (non-cumulative) counts; last slot is +Inf
+	sum     float64
+	count   uint64
+}
+
+// NewHistogram creates a histogram with the given ascending upper bounds.
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// Observe records a single value.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(h.buckets) && v > h.buckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// HistogramBucket is a single cumulative bucket: the number of observations
+// less than or equal to UpperBound.
+type HistogramBucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// HistogramSnapshot is a point-in-time copy of a Histogram. The implicit
+// +Inf bucket equals Count.
+type HistogramSnapshot struct {
+	Buckets []HistogramBucket `json:"buckets"`
+	Sum     float64           `json:"sum"`
+	Count   uint64            `json:"count"`
+}
+
+// Snapshot returns the current cumulative bucket counts, sum, and count.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snap := HistogramSnapshot{
+		Buckets: make([]HistogramBucket, len(h.buckets)),
+		Sum:     h.sum,
+		Count:   h.count,
+	}
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += h.counts[i]
+		snap.Buckets[i] = HistogramBucket{UpperBound: le, Count: cumulative}
+	}
+	return snap
+}
@@ -0,0 +1,51 @@
+package provider
+
+import "testing"
+
+func TestHistogram_CumulativeBuckets(t *testing.T) {
+	h := NewHistogram([]float64{100, 500, 1000})
+
+	for _, v := range []float64{10, 100, 250, 900, 5000} {
+		h.Observe(v)
+	}
+
+	snap := h.Snapshot()
+	want := []uint64{2, 3, 4} // le=100, le=500, le=1000
+	for i, b := range snap.Buckets {
+		if b.Count != want[i] {
+			t.Errorf("bucket le=%v count = %d, want %d", b.UpperBound, b.Count, want[i])
+		}
+	}
+	if snap.Count != 5 {
+		t.Errorf("Count = %d, want 5 (includes +Inf)", snap.Count)
+	}
+	if snap.Sum != 6260 {
+		t.Errorf("Sum = %v, want 6260", snap.Sum)
+	}
+}
+
+func TestHistogram_Empty(t *testing.T) {
+	snap := NewHistogram(defaultLatencyBucketsMs).Snapshot()
+	if snap.Count != 0 || snap.Sum != 0 {
+		t.Errorf("empty histogram: Count=%d Sum=%v, want zeros", snap.Count, snap.Sum)
+	}
+	if len(snap.Buckets) != len(defaultLatencyBucketsMs) {
+		t.Errorf("len(Buckets) = %d, want %d", len(snap.Buckets), len(defaultLatencyBucketsMs))
+	}
+}
+
+func TestRecordRequest_ObservesLatency(t *testing.T) {
+	p := newTestProvider(1, 5)
+	p.requestLatency = NewHistogram(defaultLatencyBucketsMs)
+
+	p.recordRequest(10, 320)
+
+	status := p.Status()
+	if status.RequestCount != 1 {
+		t.Errorf("RequestCount = %d, want 1", status.RequestCount)
+	}
+	if status.RequestLatencyMs.Count != 1 || status.RequestLatencyMs.Sum != 320 {
+		t.Errorf("latency histogram Count=%d Sum=%v, want 1 and 320",
+			status.RequestLatencyMs.Count, status.RequestLatencyMs.Sum)
+	}
+}
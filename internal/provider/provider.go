@@ -2,17 +2,22 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cllmhub/cllmhub-cli/internal/audit"
 	"github.com/cllmhub/cllmhub-cli/internal/auth"
 	"github.com/cllmhub/cllmhub-cli/internal/backend"
+	"github.com/cllmhub/cllmhub-cli/internal/gpustat"
 	"github.com/cllmhub/cllmhub-cli/internal/hub"
-	"github.com/google/uuid"
 	"golang.org/x/time/rate"
 )
 
@@ -26,7 +31,8 @@ const (
 
 // Provider manages the lifecycle of a published model
 type Provider struct {
-	id          string
+	idMu        sync.Mutex // guards id; separate from mu so logf works with mu held
+	id          string     // changes when a reconnect registers under a new ID
 	model       string
 	description string
 	backend     backend.Backend
@@ -39,6 +45,18 @@ type Provider struct {
 	peakInflight  int // highest observed successful concurrency
 	startTime     time.Time
 	modelServerUp bool
+	degraded      bool // failing proactive health checks but still connected
+	failedChecks  int  // consecutive failed proactive health checks
+	paused        bool // draining: finish in-flight requests, refuse new ones
+
+	heartbeatRetrying bool  // a failed heartbeat is being retried
+	heartbeatFailures int64 // heartbeat sends that failed, for status
+
+	stopErr error // why the provider stopped itself, returned by Start
+
+	requestLatency *Histogram // successful request latency in ms; nil-safe
+
+	inflight map[string]struct{} // request IDs being handled; lazily created
 
 	// AIMD concurrency control
 	maxSlots          int       // current slot limit (reported to hub)
@@ -48,48 +66,107 @@ type Provider struct {
 	slots             chan struct{} // semaphore for local enforcement
 	updateHubSlots    func(int) error // sends slot update to hub; nil-safe
 
-	watch bool // proactively watch backend health
+	watch          bool          // proactively watch backend health
+	healthInterval time.Duration // proactive health check period
+
+	recoveryInterval time.Duration // between health checks while the backend is down
 
-	ctx    context.Context
-	cancel context.CancelFunc
+	maxTokensLimit int           // 0 = no limit
+	requestTimeout time.Duration // 0 = no per-request deadline
+
+	maxQueue  int           // requests allowed to wait for a slot; 0 = no limit
+	waitq     []*slotWaiter // requests waiting for a slot, highest priority first
+	waitSeq   uint64        // arrival counter, keeps equal priorities FIFO
+	slotFreed chan struct{} // closed when a slot is released; nil if nobody waits
+
+	streamCoalesce time.Duration // batch stream tokens for this long; 0 = send each
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	handlers sync.WaitGroup // in-flight request handlers
 
 	audit    *audit.Logger
 	limiter  *rate.Limiter
 	tokenMgr *auth.TokenManager
 	logger   *slog.Logger
+
+	eventHandler EventHandler // nil = no events
+
+	gpu *gpustat.Sampler // GPU utilization for heartbeats; nil-safe
 }
 
+// ErrBackendDown is returned by Start when the provider unpublished itself
+// because its backend stayed unreachable.
+var ErrBackendDown = errors.New("backend unreachable")
+
 // Config holds provider configuration
 type Config struct {
 	Model         string
 	Description   string
 	Token         string
-	Backend       backend.Config
+	Backend       backend.Config // Backend.Model empty = Model
 	HubURL        string
 	LogFile       string
 	RateLimit     int // requests per minute, 0 = unlimited
 	MaxConcurrent int // optional ceiling hint; 0 = use default (5)
 	TokenManager  *auth.TokenManager
-	Logger        *slog.Logger // optional; if nil, prints to stdout
-	Watch         bool         // proactively watch backend health
+	Logger        *slog.Logger  // optional; if nil, prints to stdout
+	Watch         bool          // proactively watch backend health
+	WatchInterval time.Duration // health check period with Watch; 0 = 30s
+
+	MaxTokensLimit int           // clamp for requested max_tokens, also used when none is requested; 0 = no limit
+	RequestTimeout time.Duration // per-request backend deadline; 0 = none
+
+	HealthRetries       int           // extra startup health checks before giving up; 0 = check once
+	HealthRetryInterval time.Duration // delay before the first retry, doubling after each; 0 = 2s
+
+	Events EventHandler // optional lifecycle callbacks, in addition to logging
+
+	MaxQueue int // requests allowed to wait for a free slot before new ones are rejected; 0 = no limit
+
+	ProviderID string // ID to register under; empty = random. See StableProviderID.
+
+	StreamCoalesce time.Duration // combine stream tokens produced within this window into one message; 0 = off
 }
 
 // New creates a new provider instance
 func New(cfg Config) (*Provider, error) {
+	if cfg.ProviderID != "" {
+		if err := ValidateProviderID(cfg.ProviderID); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Backend.Model == "" {
+		cfg.Backend.Model = cfg.Model
+	}
+
 	// Create backend
 	b, err := backend.New(cfg.Backend)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create backend: %w", err)
 	}
 
-	// Check backend health
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := b.Health(ctx); err != nil {
+	// Check backend health, retrying in case it is still starting up.
+	if err := waitHealthy(b, cfg); err != nil {
 		return nil, fmt.Errorf("backend health check failed: %w", err)
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	backendModel := resolveBackendModel(ctx, b, cfg.Backend.Model)
+	if backendModel == "" && cfg.Backend.Model != cfg.Model {
+		backendModel = cfg.Backend.Model
+	}
 
-	providerID := uuid.New().String()[:8]
+	providerID := cfg.ProviderID
+	if providerID == "" {
+		providerID = newProviderID()
+	}
+
+	healthInterval := defaultHealthInterval
+	if cfg.WatchInterval > 0 {
+		healthInterval = cfg.WatchInterval
+	}
 
 	// Determine slot ceiling: user hint or default.
 	slotCeiling := defaultMaxSlots
@@ -112,6 +189,7 @@ func New(cfg Config) (*Provider, error) {
 		Description:   cfg.Description,
 		MaxConcurrent: initialSlots,
 		Token:         cfg.Token,
+		BackendModel:  backendModel,
 	}
 
 	// Connect to hub via WebSocket
@@ -119,6 +197,9 @@ func New(cfg Config) (*Provider, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to hub: %w", err)
 	}
+	// Keep a gateway-assigned ID, and ask for it again on reconnect.
+	providerID = hubClient.ProviderID()
+	hubCfg.ProviderID = providerID
 
 	p := &Provider{
 		id:            providerID,
@@ -133,9 +214,19 @@ func New(cfg Config) (*Provider, error) {
 		slotCeiling:   slotCeiling,
 		slots:         make(chan struct{}, initialSlots),
 		updateHubSlots: hubClient.UpdateMaxConcurrent,
+		requestLatency: NewHistogram(defaultLatencyBucketsMs),
 		watch:         cfg.Watch,
+		healthInterval: healthInterval,
+		recoveryInterval: healthCheckInterval,
+		maxTokensLimit: cfg.MaxTokensLimit,
+		requestTimeout: cfg.RequestTimeout,
+		maxQueue:       cfg.MaxQueue,
+		streamCoalesce: cfg.StreamCoalesce,
 		tokenMgr:      cfg.TokenManager,
 		logger:        cfg.Logger,
+
+		eventHandler: cfg.Events,
+		gpu:          gpustat.NewSampler(gpuSampleTTL),
 	}
 
 	// Give the hub client access to fresh tokens for HTTP requests (alerts).
@@ -162,14 +253,97 @@ func New(cfg Config) (*Provider, error) {
 	return p, nil
 }
 
+const (
+	healthCheckTimeout         = 10 * time.Second
+	defaultHealthRetryInterval = 2 * time.Second
+	maxHealthRetryInterval     = 30 * time.Second
+)
+
+// waitHealthy runs the startup health check, retrying up to cfg.HealthRetries
+// times with exponential backoff. It returns the last error if the backend
+// never becomes healthy.
+func waitHealthy(b backend.Backend, cfg Config) error {
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		err := b.Health(ctx)
+		cancel()
+		if err == nil || attempt >= cfg.HealthRetries {
+			return err
+		}
+
+		delay := healthRetryDelay(cfg.HealthRetryInterval, attempt)
+		if cfg.Logger != nil {
+			cfg.Logger.Warn("backend not healthy yet, retrying", "model", cfg.Model, "attempt", attempt+1, "retries", cfg.HealthRetries, "retry_in", delay, "error", err)
+		} else {
+			fmt.Printf("⚠ Backend not healthy yet (attempt %d/%d), retrying in %s: %v\n", attempt+1, cfg.HealthRetries+1, delay, err)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// healthRetryDelay returns the wait before retry number attempt (0-based):
+// interval doubled per attempt, capped at maxHealthRetryInterval.
+func healthRetryDelay(interval time.Duration, attempt int) time.Duration {
+	if interval <= 0 {
+		interval = defaultHealthRetryInterval
+	}
+	delay := interval
+	for i := 0; i < attempt && delay < maxHealthRetryInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, maxHealthRetryInterval)
+}
+
+// HealthRetryBudget is the longest New can spend on startup health checks
+// with the given retry settings, so callers can size their own timeouts.
+func HealthRetryBudget(retries int, interval time.Duration) time.Duration {
+	budget := healthCheckTimeout
+	for attempt := 0; attempt < retries; attempt++ {
+		budget += healthRetryDelay(interval, attempt) + healthCheckTimeout
+	}
+	return budget
+}
+
+// resolveBackendModel returns the backend's own name for model when it
+// differs from the advertised one, for transparency in the catalog. It
+// returns "" when the names match, the backend cannot list models, or the
+// match is ambiguous.
+func resolveBackendModel(ctx context.Context, b backend.Backend, model string) string {
+	models, err := b.ListModels(ctx)
+	if err != nil || len(models) == 0 {
+		return ""
+	}
+
+	var tagged []string
+	for _, m := range models {
+		if m == model {
+			return ""
+		}
+		if strings.HasPrefix(m, model+":") {
+			tagged = append(tagged, m)
+		}
+	}
+	// Ollama resolves an untagged name to its :latest tag.
+	for _, m := range tagged {
+		if m == model+":latest" {
+			return m
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0]
+	}
+	return ""
+}
+
 // Start begins listening for inference requests.
 // If the WebSocket connection drops, it automatically reconnects once per minute.
 func (p *Provider) Start(ctx context.Context) error {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
 	p.logf("✓ Connected to cLLMHub network\n")
-	p.logf("✓ Model %q published as %s (slots: %d, ceiling: %d)\n", p.model, p.id, p.maxSlots, p.slotCeiling)
+	p.logf("✓ Model %q published as %s (slots: %d, ceiling: %d)\n", p.model, p.providerID(), p.maxSlots, p.slotCeiling)
 	p.logf("✓ Listening for requests via WebSocket\n")
+	p.events().OnConnected(p.providerID())
 
 	// Watch for token manager death and shut down the provider.
 	if p.tokenMgr != nil {
@@ -195,10 +369,13 @@ func (p *Provider) Start(ctx context.Context) error {
 	for {
 		// Block on the read loop — dispatches requests to handleRequest.
 		// On each hub ping, reply with a heartbeat to refresh the provider TTL.
-		err := p.hub.ReadLoop(p.ctx, p.handleRequest, p.sendHeartbeat)
+		err := p.hub.ReadLoop(p.ctx, p.dispatch, p.sendHeartbeat)
 
 		// If the parent context was cancelled, this is a deliberate shutdown.
 		if p.ctx.Err() != nil {
+			if stopErr := p.stoppedWith(); stopErr != nil {
+				return stopErr
+			}
 			return err
 		}
 
@@ -210,11 +387,19 @@ func (p *Provider) Start(ctx context.Context) error {
 		if !up {
 			// Wait for recovery (onModelServerDown) or shutdown.
 			<-p.ctx.Done()
+			if stopErr := p.stoppedWith(); stopErr != nil {
+				return stopErr
+			}
 			return p.ctx.Err()
 		}
 
 		// Connection dropped unexpectedly — attempt to reconnect.
+		// Requests in flight now can no longer be answered; remember them
+		// so the gateway can fail them fast once we are back.
+		interrupted := p.inflightIDs()
+		p.hub.Close()
 		p.logf("\n⚠ Connection lost: %v\n", err)
+		p.events().OnDisconnected(err)
 		p.logf("  Will attempt to reconnect every 60 seconds...\n")
 
 		if !p.reconnectLoop() {
@@ -223,14 +408,39 @@ func (p *Provider) Start(ctx context.Context) error {
 			}
 			return fmt.Errorf("failed to reconnect after %d attempts", maxReconnectAttempts)
 		}
+		p.failInterrupted(interrupted)
+	}
+}
+
+// failInterrupted reports requests that were in flight when the previous
+// connection dropped, so the gateway fails them instead of waiting for a
+// timeout.
+func (p *Provider) failInterrupted(requestIDs []string) {
+	if len(requestIDs) == 0 {
+		return
+	}
+	p.logf("⚠ Failing %d request(s) interrupted by the reconnect\n", len(requestIDs))
+	for _, id := range requestIDs {
+		p.hub.SendError(id, "provider connection interrupted, please retry")
 	}
 }
 
 const (
 	maxReconnectAttempts    = 5
-	maxHealthCheckAttempts  = 2
+	maxHealthCheckAttempts  = 2 // consecutive failed recovery checks before giving up with ErrBackendDown
 	healthCheckInterval     = 60 * time.Second
-	proactiveHealthInterval = 30 * time.Second
+	defaultHealthInterval   = 30 * time.Second
+	maxDegradedChecks       = 3 // consecutive failed proactive checks before unpublishing
+	handlerShutdownTimeout  = 10 * time.Second
+	gpuSampleTTL            = 15 * time.Second
+)
+
+const (
+	// Consecutive failed heartbeat sends before the connection is treated
+	// as dead and dropped, so Start reconnects.
+	maxHeartbeatFailures = 3
+	heartbeatRetryBase   = time.Second
+	heartbeatRetryMax    = 10 * time.Second
 )
 
 // reconnectLoop tries to re-establish the hub WebSocket.
@@ -254,6 +464,7 @@ func (p *Provider) reconnectLoop() bool {
 		}
 
 		p.logf("⚠ Reconnect attempt %d/%d...\n", attempt, maxReconnectAttempts)
+		p.events().OnReconnect(attempt)
 
 		// Use a fresh token if available.
 		cfg := p.hubCfg
@@ -270,7 +481,9 @@ func (p *Provider) reconnectLoop() bool {
 		}
 
 		p.hub = newClient
+		p.adoptProviderID(newClient.ProviderID())
 		p.logf("✓ Reconnected to cLLMHub network\n")
+		p.events().OnConnected(p.providerID())
 		return true
 	}
 
@@ -278,10 +491,35 @@ func (p *Provider) reconnectLoop() bool {
 	return false
 }
 
+// adoptProviderID switches to the provider ID the gateway registered a new
+// connection under, in case it assigned a different one.
+func (p *Provider) adoptProviderID(id string) {
+	if id == "" {
+		return
+	}
+	p.idMu.Lock()
+	p.id = id
+	p.idMu.Unlock()
+
+	p.mu.Lock()
+	p.hubCfg.ProviderID = id
+	p.mu.Unlock()
+}
+
+// providerID returns the ID the provider is currently registered under.
+func (p *Provider) providerID() string {
+	p.idMu.Lock()
+	defer p.idMu.Unlock()
+	return p.id
+}
+
 // healthCheckLoop periodically pings the backend to detect it going down
-// even when no inference requests are flowing.
+// even when no inference requests are flowing. A failed check marks the
+// provider degraded so the gateway stops routing to it; it is only
+// unpublished if the backend stays unhealthy for maxDegradedChecks checks.
+// This rides out short outages such as Ollama reloading a model.
 func (p *Provider) healthCheckLoop() {
-	ticker := time.NewTicker(proactiveHealthInterval)
+	ticker := time.NewTicker(p.healthInterval)
 	defer ticker.Stop()
 
 	for {
@@ -300,14 +538,47 @@ func (p *Provider) healthCheckLoop() {
 			err := p.backend.Health(ctx)
 			cancel()
 
-			if err != nil {
-				p.logf("⚠ Proactive health check failed: %v\n", err)
+			changed, down := p.recordHealthCheck(err == nil)
+			switch {
+			case down:
+				p.logf("⚠ Proactive health check failed %d times in a row: %v\n", maxDegradedChecks, err)
 				go p.onModelServerDown()
+			case changed && err != nil:
+				p.logf("⚠ Proactive health check failed, marking model degraded: %v\n", err)
+				p.sendHeartbeat()
+			case changed:
+				p.logf("✓ Model server healthy again, marking model online\n")
+				p.sendHeartbeat()
 			}
 		}
 	}
 }
 
+// recordHealthCheck updates the degraded state from a proactive health check.
+// It reports whether the degraded state changed and whether the backend has
+// been unhealthy long enough to be treated as down.
+func (p *Provider) recordHealthCheck(healthy bool) (changed, down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if healthy {
+		p.failedChecks = 0
+		changed = p.degraded
+		p.degraded = false
+		return changed, false
+	}
+
+	p.failedChecks++
+	if p.failedChecks >= maxDegradedChecks {
+		p.failedChecks = 0
+		p.degraded = false
+		return false, true
+	}
+	changed = !p.degraded
+	p.degraded = true
+	return changed, false
+}
+
 // onModelServerDown is triggered when a backend request fails with a connection error.
 // It unpublishes the model immediately, runs health checks, and either republishes or stays unpublished.
 func (p *Provider) onModelServerDown() {
@@ -321,23 +592,27 @@ func (p *Provider) onModelServerDown() {
 
 	p.logf("\n⚠ Model server unreachable: %s\n", p.backend.URL())
 
-	// Unpublish: close the hub WebSocket so the model is no longer available.
-	// Close first so the model is removed from the hub immediately.
+	// Unpublish: unregister and close the hub WebSocket so the model is
+	// removed from the hub immediately rather than at heartbeat expiry.
 	p.logf("⚠ Unpublishing model %q\n", p.model)
+	if err := p.hub.SendUnpublishWithReason(hub.UnregisterBackendDown); err != nil {
+		p.logf("⚠ Failed to send unregister: %v\n", err)
+	}
 	p.hub.Close()
+	p.events().OnDisconnected(fmt.Errorf("model server unreachable at %s", p.backend.URL()))
 
 	// Alert: model_server_unreachable (async — don't delay unpublish)
 	go p.hub.SendAlert(hub.Alert{
-		ProviderID: p.id,
+		ProviderID: p.providerID(),
 		Model:      p.model,
 		AlertType:  "model_server_unreachable",
 		Message:    fmt.Sprintf("Model server unreachable at %s, unpublishing model", p.backend.URL()),
 		Timestamp:  time.Now(),
 	})
 
-	p.logf("  Will check again — %d attempts, 60 seconds apart...\n", maxHealthCheckAttempts)
+	p.logf("  Will check again — %d attempts, %s apart...\n", maxHealthCheckAttempts, p.recoveryInterval)
 
-	ticker := time.NewTicker(healthCheckInterval)
+	ticker := time.NewTicker(p.recoveryInterval)
 	defer ticker.Stop()
 
 	for attempt := 1; attempt <= maxHealthCheckAttempts; attempt++ {
@@ -371,6 +646,7 @@ func (p *Provider) onModelServerDown() {
 			}
 
 			p.hub = newClient
+			p.adoptProviderID(newClient.ProviderID())
 			if p.tokenMgr != nil {
 				p.hub.SetTokenFunc(p.tokenMgr.AccessToken)
 			}
@@ -386,9 +662,10 @@ func (p *Provider) onModelServerDown() {
 			p.mu.Unlock()
 
 			p.logf("✓ Model %q republished\n", p.model)
+			p.events().OnConnected(p.providerID())
 
 			p.hub.SendAlert(hub.Alert{
-				ProviderID: p.id,
+				ProviderID: p.providerID(),
 				Model:      p.model,
 				AlertType:  "model_server_recovered",
 				Message:    "Model server recovered, model republished",
@@ -405,14 +682,26 @@ func (p *Provider) onModelServerDown() {
 	p.logf("✗ Model server down after %d attempts, staying unpublished\n", maxHealthCheckAttempts)
 
 	p.hub.SendAlert(hub.Alert{
-		ProviderID: p.id,
+		ProviderID: p.providerID(),
 		Model:      p.model,
 		AlertType:  "model_server_down",
 		Message:    fmt.Sprintf("Model server down after %d attempts, model stays unpublished", maxHealthCheckAttempts),
 		Timestamp:  time.Now(),
 	})
 
-	p.Stop()
+	// Give up: Start returns the error so a supervisor can restart us. The
+	// unregister was already sent when the model was unpublished above.
+	p.mu.Lock()
+	p.stopErr = fmt.Errorf("%w: %s still down after %d health checks", ErrBackendDown, p.backend.URL(), maxHealthCheckAttempts)
+	p.mu.Unlock()
+	p.shutdown()
+}
+
+// stoppedWith returns the error the provider stopped itself with, if any.
+func (p *Provider) stoppedWith() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopErr
 }
 
 // CloseConnection closes the current WebSocket without stopping the provider,
@@ -425,19 +714,39 @@ func (p *Provider) CloseConnection() {
 
 // Stop gracefully shuts down the provider
 func (p *Provider) Stop() {
+	p.StopWithReason(hub.UnregisterShutdown)
+}
+
+// StopWithReason is Stop, telling the gateway why the provider is leaving
+// (one of the hub.Unregister* reasons).
+func (p *Provider) StopWithReason(reason string) {
 	if p.hub != nil {
 		// Send unregister while the WebSocket is still open.
-		p.logf("⚠ Unregistering model %q (provider %s)\n", p.model, p.id)
-		if err := p.hub.SendUnpublish(); err != nil {
+		p.logf("⚠ Unregistering model %q (provider %s, reason: %s)\n", p.model, p.providerID(), reason)
+		if err := p.hub.SendUnpublishWithReason(reason); err != nil {
 			p.logf("✗ Failed to send unregister: %v\n", err)
 		} else {
 			p.logf("✓ Unregister message sent for model %q\n", p.model)
 		}
 	}
+	p.shutdown()
+}
+
+// shutdown stops the provider and closes the hub connection without
+// sending an unregister, for when the gateway has already been told.
+func (p *Provider) shutdown() {
 	// Cancel the context so ReadLoop and Start() know this is a
-	// deliberate shutdown and don't attempt to reconnect.
+	// deliberate shutdown and don't attempt to reconnect. Cancelled under
+	// p.mu so dispatch stops adding handlers before waitHandlers runs.
 	if p.cancel != nil {
+		p.mu.Lock()
 		p.cancel()
+		p.mu.Unlock()
+	}
+	// Cancelling aborts backend calls; give handlers a moment to report
+	// the failure to the gateway before the connection goes away.
+	if !p.waitHandlers(handlerShutdownTimeout) {
+		p.logf("⚠ Timed out waiting for in-flight requests to finish\n")
 	}
 	if p.hub != nil {
 		p.hub.Disconnect()
@@ -520,20 +829,77 @@ func (p *Provider) resizeSlots(size int) {
 	p.slots = make(chan struct{}, size)
 }
 
+// dispatch runs handleRequest on its own goroutine, tracked so Stop can
+// wait for in-flight requests. Requests arriving once Stop has cancelled the
+// provider are refused: the check and the Add share p.mu with the cancel in
+// StopWithReason, so no Add can race the Wait that follows it.
+func (p *Provider) dispatch(req hub.RequestMsg) {
+	p.mu.Lock()
+	if p.ctx.Err() != nil {
+		p.mu.Unlock()
+		p.hub.SendError(req.RequestID, "provider is shutting down")
+		return
+	}
+	p.handlers.Add(1)
+	p.mu.Unlock()
+	go func() {
+		defer p.handlers.Done()
+		p.handleRequest(req)
+	}()
+}
+
+// waitHandlers waits up to timeout for in-flight handlers to return.
+// It reports whether they all finished.
+func (p *Provider) waitHandlers(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		p.handlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 // handleRequest processes incoming inference requests from the hub
 func (p *Provider) handleRequest(req hub.RequestMsg) {
-	// Reject requests while model server is down
+	// Reply on the connection the request arrived on. If that connection
+	// drops, late replies fail with hub.ErrClosed instead of reaching the
+	// gateway after failInterrupted has already reported the request as failed.
+	h := p.hub
+
+	p.trackInflight(req.RequestID)
+	defer p.untrackInflight(req.RequestID)
+	p.events().OnRequest(req.RequestID)
+
+	// A bug in a backend parser must fail this request, not the daemon.
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[%s] panic handling request: %v", req.RequestID, r)
+			h.SendError(req.RequestID, "internal provider error")
+		}
+	}()
+
+	// Reject requests while model server is down or the provider is paused
 	p.mu.Lock()
 	up := p.modelServerUp
+	paused := p.paused
 	p.mu.Unlock()
 	if !up {
-		p.hub.SendError(req.RequestID, "model server temporarily unavailable")
+		h.SendError(req.RequestID, "model server temporarily unavailable")
+		return
+	}
+	if paused {
+		h.SendError(req.RequestID, "provider is paused")
 		return
 	}
 
 	// Rate limit check
 	if p.limiter != nil && !p.limiter.Allow() {
-		p.hub.SendError(req.RequestID, "rate limit exceeded")
+		h.SendError(req.RequestID, "rate limit exceeded")
 		p.audit.Log(audit.Entry{
 			RequestID: req.RequestID,
 			Model:     req.Model,
@@ -544,18 +910,21 @@ func (p *Provider) handleRequest(req hub.RequestMsg) {
 	}
 
 	// Local semaphore: enforce max concurrent slots regardless of hub.
-	// Take a snapshot of the current semaphore under the lock so we
-	// use a consistent channel even if resizeSlots runs concurrently.
-	p.mu.Lock()
-	sem := p.slots
-	p.mu.Unlock()
-
-	select {
-	case sem <- struct{}{}:
-	case <-p.ctx.Done():
+	sem, busy := p.acquireSlot(req.Params.Priority)
+	if busy {
+		h.SendError(req.RequestID, "provider busy")
+		p.audit.Log(audit.Entry{
+			RequestID: req.RequestID,
+			Model:     req.Model,
+			Stream:    req.Params.Stream,
+			Error:     "provider busy",
+		})
 		return
 	}
-	defer func() { <-sem }()
+	if sem == nil {
+		return
+	}
+	defer p.releaseSlot(sem)
 
 	p.mu.Lock()
 	p.queueDepth++
@@ -570,19 +939,61 @@ func (p *Provider) handleRequest(req hub.RequestMsg) {
 
 	start := time.Now()
 
+	// A hung backend must not hold a slot forever.
+	ctx, cancel := p.ctx, context.CancelFunc(func() {})
+	if p.requestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(p.ctx, p.requestTimeout)
+	}
+	defer cancel()
+
 	backendReq := &backend.Request{
 		Prompt:      req.Prompt,
 		Messages:    req.Messages,
-		MaxTokens:   req.Params.MaxTokens,
-		Temperature: req.Params.Temperature,
-		TopP:        req.Params.TopP,
+		MaxTokens:   clampMaxTokens(req.Params.MaxTokens, p.maxTokensLimit),
+		Temperature: clamp(req.Params.Temperature, 0, maxTemperature),
+		TopP:        clamp(req.Params.TopP, 0, 1),
+		Stop:        req.Params.Stop,
+		Seed:        req.Params.Seed,
+		SessionID:   req.SessionID,
+		LogProbs:    req.Params.LogProbs,
+		TopLogProbs: req.Params.TopLogProbs,
+
+		ResponseFormat: req.Params.ResponseFormat,
+		Grammar:        req.Params.Grammar,
+
+		Prefix: req.Params.Prefix,
+		Suffix: req.Params.Suffix,
 	}
 
 	if req.Params.Stream {
-		p.handleStreamingRequest(req, backendReq, start, inflight)
+		p.handleStreamingRequest(ctx, h, req, backendReq, start, inflight)
 	} else {
-		p.handleNonStreamingRequest(req, backendReq, start, inflight)
+		p.handleNonStreamingRequest(ctx, h, req, backendReq, start, inflight)
+	}
+}
+
+// clampMaxTokens applies the provider's max_tokens limit. A requested value
+// <= 0 means "backend default" and is normalized to 0 so it is omitted from
+// backend requests; with a limit set it becomes the limit, so the limit
+// cannot be bypassed by asking for "unlimited".
+func clampMaxTokens(requested, limit int) int {
+	if requested < 0 {
+		requested = 0
+	}
+	if limit > 0 && (requested == 0 || requested > limit) {
+		return limit
 	}
+	return requested
+}
+
+// maxTemperature is the highest sampling temperature passed to backends,
+// matching the OpenAI API range.
+const maxTemperature = 2.0
+
+// clamp limits v to [lo, hi]. Gateway params are clamped defensively because
+// out-of-range values make some backends error and others produce garbage.
+func clamp(v, lo, hi float64) float64 {
+	return min(max(v, lo), hi)
 }
 
 // sanitizeError logs the full error locally and returns a generic message for the hub.
@@ -591,17 +1002,141 @@ func sanitizeError(requestID string, err error) string {
 	return "internal backend error"
 }
 
-func (p *Provider) handleNonStreamingRequest(req hub.RequestMsg, backendReq *backend.Request, start time.Time, inflight int) {
-	resp, err := p.backend.Complete(p.ctx, backendReq)
+// slotWaiter is a request waiting in acquireSlot.
+type slotWaiter struct {
+	rank int
+	seq  uint64
+}
+
+// priorityRank orders hub priorities; unknown values count as normal.
+func priorityRank(priority string) int {
+	switch priority {
+	case hub.PriorityHigh:
+		return 2
+	case hub.PriorityLow:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// acquireSlot takes a slot from the current semaphore and returns it; the
+// caller hands it back with releaseSlot. While all slots are busy, requests
+// wait in priority order (FIFO within a priority). If maxQueue requests are
+// already waiting it reports busy instead, so an overwhelmed provider fails
+// fast and the gateway can route elsewhere. sem is nil if the provider
+// stopped while waiting.
+func (p *Provider) acquireSlot(priority string) (sem chan struct{}, busy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The semaphore is re-read on every attempt: resizeSlots may swap it
+	// while we wait.
+	if len(p.waitq) == 0 {
+		select {
+		case p.slots <- struct{}{}:
+			return p.slots, false
+		default:
+		}
+	}
+	if p.maxQueue > 0 && len(p.waitq) >= p.maxQueue {
+		return nil, true
+	}
+
+	p.waitSeq++
+	w := &slotWaiter{rank: priorityRank(priority), seq: p.waitSeq}
+	i := sort.Search(len(p.waitq), func(i int) bool {
+		q := p.waitq[i]
+		return q.rank < w.rank || (q.rank == w.rank && q.seq > w.seq)
+	})
+	p.waitq = slices.Insert(p.waitq, i, w)
+	defer func() {
+		if i := slices.Index(p.waitq, w); i >= 0 {
+			p.waitq = slices.Delete(p.waitq, i, i+1)
+		}
+		if len(p.waitq) > 0 {
+			// Let the next waiter in line check for a free slot.
+			p.wakeWaitersLocked()
+		}
+	}()
+
+	for {
+		if p.waitq[0] == w {
+			select {
+			case p.slots <- struct{}{}:
+				return p.slots, false
+			default:
+			}
+		}
+		if p.slotFreed == nil {
+			p.slotFreed = make(chan struct{})
+		}
+		freed := p.slotFreed
+
+		p.mu.Unlock()
+		select {
+		case <-freed:
+			p.mu.Lock()
+		case <-p.ctx.Done():
+			p.mu.Lock()
+			return nil, false
+		}
+	}
+}
+
+// releaseSlot returns a slot taken by acquireSlot and wakes any waiters.
+func (p *Provider) releaseSlot(sem chan struct{}) {
+	<-sem
+	p.mu.Lock()
+	p.wakeWaitersLocked()
+	p.mu.Unlock()
+}
+
+// wakeWaitersLocked wakes every goroutine blocked in acquireSlot so the one
+// at the head of the queue can retry. Caller holds p.mu.
+func (p *Provider) wakeWaitersLocked() {
+	if p.slotFreed != nil {
+		close(p.slotFreed)
+		p.slotFreed = nil
+	}
+}
+
+// timedOut reports whether a backend call failed because its per-request
+// deadline passed, as opposed to the provider shutting down.
+func (p *Provider) timedOut(ctx context.Context) bool {
+	return ctx.Err() == context.DeadlineExceeded && p.ctx.Err() == nil
+}
+
+// timeoutMessage is the error sent to the gateway when a request exceeds
+// the provider's request timeout.
+func (p *Provider) timeoutMessage() string {
+	return fmt.Sprintf("request timed out after %s", p.requestTimeout)
+}
+
+func (p *Provider) handleNonStreamingRequest(ctx context.Context, h *hub.HubClient, req hub.RequestMsg, backendReq *backend.Request, start time.Time, inflight int) {
+	resp, err := p.backend.Complete(ctx, backendReq)
 	if err != nil {
+		p.events().OnError(req.RequestID, err)
+		if p.timedOut(ctx) {
+			msg := p.timeoutMessage()
+			h.SendError(req.RequestID, msg)
+			p.audit.Log(audit.Entry{
+				RequestID: req.RequestID,
+				Model:     req.Model,
+				Stream:    false,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Error:     msg,
+			})
+			return
+		}
 		if backend.IsConnectionError(err) {
-			p.hub.SendError(req.RequestID, "model server temporarily unavailable")
+			h.SendError(req.RequestID, "model server temporarily unavailable")
 			go p.onModelServerDown()
 			p.reduceSlots(inflight)
 			return
 		}
 		msg := sanitizeError(req.RequestID, err)
-		p.hub.SendError(req.RequestID, msg)
+		h.SendError(req.RequestID, msg)
 		p.audit.Log(audit.Entry{
 			RequestID: req.RequestID,
 			Model:     req.Model,
@@ -616,14 +1151,14 @@ func (p *Provider) handleNonStreamingRequest(req hub.RequestMsg, backendReq *bac
 
 	latency := time.Since(start).Milliseconds()
 
-	p.hub.SendResponse(req.RequestID, resp.Text, p.id, latency, hub.Usage{
+	h.SendResponse(req.RequestID, resp.Text, p.providerID(), latency, hub.Usage{
 		PromptTokens:     resp.PromptTokens,
 		CompletionTokens: resp.CompletionTokens,
 		TotalTokens:      resp.PromptTokens + resp.CompletionTokens,
-	})
+	}, resp.LogProbs)
 
 	tokens := resp.PromptTokens + resp.CompletionTokens
-	p.recordRequest(tokens)
+	p.recordRequest(tokens, latency)
 	p.audit.Log(audit.Entry{
 		RequestID: req.RequestID,
 		Model:     req.Model,
@@ -633,29 +1168,56 @@ func (p *Provider) handleNonStreamingRequest(req hub.RequestMsg, backendReq *bac
 	})
 }
 
-func (p *Provider) handleStreamingRequest(req hub.RequestMsg, backendReq *backend.Request, start time.Time, inflight int) {
+func (p *Provider) handleStreamingRequest(ctx context.Context, h *hub.HubClient, req hub.RequestMsg, backendReq *backend.Request, start time.Time, inflight int) {
 	tokenIndex := 0
+	sendToken := func(token string) error {
+		err := h.SendStreamToken(req.RequestID, token, tokenIndex, false, "", nil)
+		tokenIndex++
+		return err
+	}
+	emit := sendToken
+	var coalescer *streamCoalescer
+	if p.streamCoalesce > 0 {
+		coalescer = newStreamCoalescer(p.streamCoalesce, sendToken)
+		emit = coalescer.add
+	}
 
 	// Don't send done=true in the per-token callback; we send the final
 	// done message after the loop with full text and usage attached.
-	resp, err := p.backend.Stream(p.ctx, backendReq, func(token string, done bool) error {
+	resp, err := p.backend.Stream(ctx, backendReq, func(token string, done bool) error {
 		if done {
 			return nil // skip — final message sent below
 		}
-		err := p.hub.SendStreamToken(req.RequestID, token, tokenIndex, false, "", nil)
-		tokenIndex++
-		return err
+		return emit(token)
 	})
+	if coalescer != nil {
+		// Flush before the done message (or error) so no buffered token
+		// trails it.
+		coalescer.flush()
+	}
 
 	if err != nil {
+		p.events().OnError(req.RequestID, err)
+		if p.timedOut(ctx) {
+			msg := p.timeoutMessage()
+			h.SendError(req.RequestID, msg)
+			p.audit.Log(audit.Entry{
+				RequestID: req.RequestID,
+				Model:     req.Model,
+				Stream:    true,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Error:     msg,
+			})
+			return
+		}
 		if backend.IsConnectionError(err) {
-			p.hub.SendError(req.RequestID, "model server temporarily unavailable")
+			h.SendError(req.RequestID, "model server temporarily unavailable")
 			go p.onModelServerDown()
 			p.reduceSlots(inflight)
 			return
 		}
 		msg := sanitizeError(req.RequestID, err)
-		p.hub.SendError(req.RequestID, msg)
+		h.SendError(req.RequestID, msg)
 		p.audit.Log(audit.Entry{
 			RequestID: req.RequestID,
 			Model:     req.Model,
@@ -674,11 +1236,11 @@ func (p *Provider) handleStreamingRequest(req hub.RequestMsg, backendReq *backen
 		CompletionTokens: resp.CompletionTokens,
 		TotalTokens:      resp.PromptTokens + resp.CompletionTokens,
 	}
-	p.hub.SendStreamToken(req.RequestID, "", tokenIndex, true, resp.Text, usage)
+	h.SendStreamToken(req.RequestID, "", tokenIndex, true, resp.Text, usage)
 
 	tokens := resp.PromptTokens + resp.CompletionTokens
 	latency := time.Since(start).Milliseconds()
-	p.recordRequest(tokens)
+	p.recordRequest(tokens, latency)
 	p.audit.Log(audit.Entry{
 		RequestID: req.RequestID,
 		Model:     req.Model,
@@ -688,47 +1250,221 @@ func (p *Provider) handleStreamingRequest(req hub.RequestMsg, backendReq *backen
 	})
 }
 
-func (p *Provider) recordRequest(tokens int) {
+// trackInflight records a request ID as being handled.
+func (p *Provider) trackInflight(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inflight == nil {
+		p.inflight = make(map[string]struct{})
+	}
+	p.inflight[id] = struct{}{}
+}
+
+// untrackInflight removes a request ID once its handler returns.
+func (p *Provider) untrackInflight(id string) {
+	p.mu.Lock()
+	delete(p.inflight, id)
+	p.mu.Unlock()
+}
+
+// inflightIDs returns the IDs of requests currently being handled.
+func (p *Provider) inflightIDs() []string {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	ids := make([]string, 0, len(p.inflight))
+	for id := range p.inflight {
+		ids = append(ids, id)
+	}
+	return ids
+}
 
+func (p *Provider) recordRequest(tokens int, latencyMs int64) {
+	p.mu.Lock()
 	p.requestCount++
+	p.mu.Unlock()
+
+	if p.requestLatency != nil {
+		p.requestLatency.Observe(float64(latencyMs))
+	}
 }
 
+// sendHeartbeat reports queue depth and status to the gateway. A failed
+// send is retried in the background rather than waiting for the next hub
+// ping, during which the gateway might expire the provider.
 func (p *Provider) sendHeartbeat() {
+	h := p.hub
+	err := p.trySendHeartbeat(h)
+	if err == nil {
+		return
+	}
+	// ErrClosed means the connection was shut down on purpose (or already
+	// dropped and is being replaced); there is nothing to retry on.
+	if errors.Is(err, hub.ErrClosed) {
+		return
+	}
+
+	p.mu.Lock()
+	p.heartbeatFailures++
+	retrying := p.heartbeatRetrying
+	p.heartbeatRetrying = true
+	p.mu.Unlock()
+	if retrying {
+		return
+	}
+	p.warnf("⚠ Heartbeat failed: %v (retrying)\n", err)
+	go p.retryHeartbeat(h)
+}
+
+func (p *Provider) trySendHeartbeat(h *hub.HubClient) error {
 	p.mu.Lock()
 	queueDepth := p.queueDepth
+	status := p.statusLocked()
 	p.mu.Unlock()
 
 	var token string
 	if p.tokenMgr != nil {
 		token = p.tokenMgr.AccessToken()
 	}
-	p.hub.SendHeartbeatWithToken(queueDepth, 0, token)
+	return h.SendHeartbeatWithToken(queueDepth, p.gpu.Utilization(), token, status)
+}
+
+// retryHeartbeat resends the heartbeat on h with backoff and jitter. If
+// maxHeartbeatFailures sends in a row fail, the connection is closed so the
+// read loop in Start returns and the reconnect path takes over.
+func (p *Provider) retryHeartbeat(h *hub.HubClient) {
+	defer func() {
+		p.mu.Lock()
+		p.heartbeatRetrying = false
+		p.mu.Unlock()
+	}()
+
+	var done <-chan struct{}
+	if p.ctx != nil {
+		done = p.ctx.Done()
+	}
+	for attempt := 1; attempt < maxHeartbeatFailures; attempt++ {
+		select {
+		case <-done:
+			return
+		case <-time.After(heartbeatRetryDelay(attempt)):
+		}
+
+		err := p.trySendHeartbeat(h)
+		if err == nil {
+			p.logf("✓ Heartbeat recovered\n")
+			return
+		}
+		if errors.Is(err, hub.ErrClosed) {
+			return
+		}
+		p.mu.Lock()
+		p.heartbeatFailures++
+		p.mu.Unlock()
+		p.warnf("⚠ Heartbeat retry %d/%d failed: %v\n", attempt, maxHeartbeatFailures-1, err)
+	}
+
+	p.warnf("✗ %d heartbeats failed in a row, reconnecting\n", maxHeartbeatFailures)
+	h.Close()
+}
+
+// heartbeatRetryDelay returns the wait before heartbeat retry number attempt
+// (1-based): heartbeatRetryBase doubled per attempt, capped at
+// heartbeatRetryMax, plus up to 50% jitter so providers that lost the
+// gateway together don't retry in lockstep.
+func heartbeatRetryDelay(attempt int) time.Duration {
+	delay := heartbeatRetryBase
+	for i := 1; i < attempt && delay < heartbeatRetryMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, heartbeatRetryMax)
+	return delay + rand.N(delay/2+1)
+}
+
+// statusLocked returns the hub status for the provider. Caller holds p.mu.
+func (p *Provider) statusLocked() string {
+	if p.paused {
+		return hub.StatusPaused
+	}
+	if p.degraded {
+		return hub.StatusDegraded
+	}
+	return hub.StatusOnline
+}
+
+// SetPaused pauses or resumes the provider. While paused, in-flight requests
+// run to completion but new ones are refused, and heartbeats report
+// hub.StatusPaused so the gateway routes elsewhere. It reports whether the
+// state changed.
+func (p *Provider) SetPaused(paused bool) bool {
+	p.mu.Lock()
+	changed := p.paused != paused
+	p.paused = paused
+	p.mu.Unlock()
+	if !changed {
+		return false
+	}
+
+	if paused {
+		p.logf("⏸ Paused: finishing in-flight requests, refusing new ones\n")
+	} else {
+		p.logf("▶ Resumed: accepting requests\n")
+	}
+	if p.hub != nil {
+		// Tell the gateway now rather than at the next heartbeat.
+		p.sendHeartbeat()
+	}
+	return true
+}
+
+// Paused reports whether the provider is paused.
+func (p *Provider) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
 }
 
 // Status returns the current provider status
 func (p *Provider) Status() ProviderStatus {
+	var latency HistogramSnapshot
+	if p.requestLatency != nil {
+		latency = p.requestLatency.Snapshot()
+	}
+	gpuUtil := p.gpu.Utilization()
+	id := p.providerID()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	return ProviderStatus{
-		ProviderID:    p.id,
+		ProviderID:    id,
 		Model:         p.model,
-		Status:        "online",
+		Status:        p.statusLocked(),
 		Uptime:        int64(time.Since(p.startTime).Seconds()),
 		RequestCount:  p.requestCount,
 		QueueDepth:    p.queueDepth,
 		MaxConcurrent: p.maxSlots,
-		GPUUtil:       0,
+		GPUUtil:       gpuUtil,
 		Timestamp:     time.Now(),
+
+		RequestLatencyMs:  latency,
+		HeartbeatFailures: p.heartbeatFailures,
 	}
 }
 
 // logf prints to stdout or logs via slog if a logger is configured.
 func (p *Provider) logf(format string, args ...any) {
 	if p.logger != nil {
-		p.logger.Info(fmt.Sprintf(format, args...), "model", p.model, "provider_id", p.id)
+		// Drop the leading/trailing newlines used to space out terminal output.
+		p.logger.Info(strings.TrimSpace(fmt.Sprintf(format, args...)), "model", p.model, "provider_id", p.providerID())
+	} else {
+		fmt.Printf(format, args...)
+	}
+}
+
+// warnf is logf at warn level, for problems operators should notice.
+func (p *Provider) warnf(format string, args ...any) {
+	if p.logger != nil {
+		p.logger.Warn(strings.TrimSpace(fmt.Sprintf(format, args...)), "model", p.model, "provider_id", p.providerID())
 	} else {
 		fmt.Printf(format, args...)
 	}
@@ -745,4 +1481,7 @@ type ProviderStatus struct {
 	MaxConcurrent int       `json:"max_concurrent"`
 	GPUUtil       float64   `json:"gpu_util"`
 	Timestamp     time.Time `json:"timestamp"`
+
+	RequestLatencyMs  HistogramSnapshot `json:"request_latency_ms"`
+	HeartbeatFailures int64             `json:"heartbeat_failures"` // failed heartbeat sends since start
 }
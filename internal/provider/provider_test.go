@@ -1,10 +1,20 @@
 package provider
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/cllmhub/cllmhub-cli/internal/backend"
 	"github.com/cllmhub/cllmhub-cli/internal/hub"
+	"github.com/gorilla/websocket"
 )
 
 // newTestProvider creates a minimal Provider for testing AIMD logic.
@@ -16,6 +26,8 @@ func newTestProvider(maxSlots, ceiling int) *Provider {
 		slots:         make(chan struct{}, maxSlots),
 		modelServerUp: true,
 		hubCfg:        hub.ConnectConfig{MaxConcurrent: maxSlots},
+
+		recoveryInterval: healthCheckInterval,
 	}
 }
 
@@ -296,3 +308,830 @@ func TestAIMD_FullCycle(t *testing.T) {
 	}
 	p.mu.Unlock()
 }
+
+// --- degraded health state ---
+
+func TestRecordHealthCheck_DegradedThenRecovers(t *testing.T) {
+	p := newTestProvider(1, 5)
+
+	if changed, down := p.recordHealthCheck(false); !changed || down {
+		t.Fatalf("first failure: changed=%v down=%v, want true false", changed, down)
+	}
+	if got := p.Status().Status; got != hub.StatusDegraded {
+		t.Errorf("Status = %q after failed check, want %q", got, hub.StatusDegraded)
+	}
+
+	if changed, down := p.recordHealthCheck(true); !changed || down {
+		t.Fatalf("recovery: changed=%v down=%v, want true false", changed, down)
+	}
+	if got := p.Status().Status; got != hub.StatusOnline {
+		t.Errorf("Status = %q after recovery, want %q", got, hub.StatusOnline)
+	}
+
+	if changed, _ := p.recordHealthCheck(true); changed {
+		t.Error("healthy check while online should not change state")
+	}
+}
+
+func TestRecordHealthCheck_DownAfterConsecutiveFailures(t *testing.T) {
+	p := newTestProvider(1, 5)
+
+	for i := 1; i < maxDegradedChecks; i++ {
+		if _, down := p.recordHealthCheck(false); down {
+			t.Fatalf("down after %d failures, want %d", i, maxDegradedChecks)
+		}
+	}
+	if _, down := p.recordHealthCheck(false); !down {
+		t.Fatalf("not down after %d consecutive failures", maxDegradedChecks)
+	}
+}
+
+func TestRecordHealthCheck_RecoveryResetsFailureCount(t *testing.T) {
+	p := newTestProvider(1, 5)
+
+	for i := 1; i < maxDegradedChecks; i++ {
+		p.recordHealthCheck(false)
+	}
+	p.recordHealthCheck(true)
+	if _, down := p.recordHealthCheck(false); down {
+		t.Error("a flap after recovery should not count earlier failures")
+	}
+}
+
+// --- in-flight handler lifecycle ---
+
+// stubBackend is a minimal backend.Backend for handler tests.
+type stubBackend struct {
+	complete func(ctx context.Context, req *backend.Request) (*backend.Response, error)
+	stream   func(ctx context.Context, req *backend.Request, cb func(string, bool) error) (*backend.Response, error)
+	models   []string
+}
+
+func (s *stubBackend) Name() string                     { return "stub" }
+func (s *stubBackend) URL() string                      { return "http://stub" }
+func (s *stubBackend) Health(ctx context.Context) error { return nil }
+func (s *stubBackend) ListModels(ctx context.Context) ([]string, error) {
+	return s.models, nil
+}
+func (s *stubBackend) Complete(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+	return s.complete(ctx, req)
+}
+func (s *stubBackend) Stream(ctx context.Context, req *backend.Request, cb func(string, bool) error) (*backend.Response, error) {
+	return s.stream(ctx, req, cb)
+}
+
+func newHandlerTestProvider(b backend.Backend) *Provider {
+	p := newTestProvider(1, 5)
+	p.backend = b
+	p.hub = &hub.HubClient{} // never connected: every send returns hub.ErrClosed
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return p
+}
+
+func TestStop_CancelsMidStream(t *testing.T) {
+	started := make(chan struct{})
+	p := newHandlerTestProvider(&stubBackend{
+		stream: func(ctx context.Context, req *backend.Request, cb func(string, bool) error) (*backend.Response, error) {
+			cb("partial", false)
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	p.dispatch(hub.RequestMsg{RequestID: "r1", Params: hub.InferenceParams{Stream: true}})
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return after cancelling a streaming request")
+	}
+	if !p.waitHandlers(time.Second) {
+		t.Error("handler still running after Stop")
+	}
+}
+
+func TestHandleRequest_RecoversPanic(t *testing.T) {
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			panic("malformed backend response")
+		},
+	})
+
+	p.dispatch(hub.RequestMsg{RequestID: "r1"})
+	if !p.waitHandlers(5 * time.Second) {
+		t.Fatal("handler did not finish")
+	}
+
+	// The slot must have been released despite the panic.
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		t.Error("semaphore slot leaked after panic")
+	}
+}
+
+// --- max_tokens limit ---
+
+func TestClampMaxTokens(t *testing.T) {
+	cases := []struct {
+		requested, limit, want int
+	}{
+		{0, 0, 0},     // backend default, no limit
+		{-5, 0, 0},    // negative normalized to backend default
+		{512, 0, 512}, // no limit: pass through uncapped
+		{512, 1024, 512},
+		{4096, 1024, 1024},
+		{0, 1024, 1024}, // "unlimited" cannot bypass the limit
+		{-1, 1024, 1024},
+	}
+	for _, tc := range cases {
+		if got := clampMaxTokens(tc.requested, tc.limit); got != tc.want {
+			t.Errorf("clampMaxTokens(%d, %d) = %d, want %d", tc.requested, tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestClamp_SamplingParams(t *testing.T) {
+	cases := []struct {
+		v, lo, hi, want float64
+	}{
+		{0.7, 0, maxTemperature, 0.7},
+		{-1, 0, maxTemperature, 0},
+		{5, 0, maxTemperature, maxTemperature},
+		{1.5, 0, 1, 1},
+		{-0.2, 0, 1, 0},
+	}
+	for _, tc := range cases {
+		if got := clamp(tc.v, tc.lo, tc.hi); got != tc.want {
+			t.Errorf("clamp(%v, %v, %v) = %v, want %v", tc.v, tc.lo, tc.hi, got, tc.want)
+		}
+	}
+}
+
+// --- in-flight request tracking ---
+
+func TestInflightIDs(t *testing.T) {
+	p := newTestProvider(1, 5)
+	if ids := p.inflightIDs(); len(ids) != 0 {
+		t.Fatalf("inflightIDs = %v, want empty", ids)
+	}
+
+	p.trackInflight("a")
+	p.trackInflight("b")
+	p.untrackInflight("a")
+
+	ids := p.inflightIDs()
+	if len(ids) != 1 || ids[0] != "b" {
+		t.Errorf("inflightIDs = %v, want [b]", ids)
+	}
+}
+
+func TestDispatch_TracksInflightUntilDone(t *testing.T) {
+	release := make(chan struct{})
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			<-release
+			return &backend.Response{Text: "late"}, nil
+		},
+	})
+
+	p.dispatch(hub.RequestMsg{RequestID: "r1"})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.inflightIDs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if ids := p.inflightIDs(); len(ids) != 1 || ids[0] != "r1" {
+		t.Fatalf("inflightIDs while handling = %v, want [r1]", ids)
+	}
+
+	close(release)
+	if !p.waitHandlers(5 * time.Second) {
+		t.Fatal("handler did not finish")
+	}
+	if ids := p.inflightIDs(); len(ids) != 0 {
+		t.Errorf("inflightIDs after completion = %v, want empty", ids)
+	}
+}
+
+// fakeGateway starts a server that accepts provider registrations and
+// decodes every later message from any connection onto the returned channel.
+func fakeGateway(t *testing.T) (url string, msgs <-chan map[string]any) {
+	t.Helper()
+	ch := make(chan map[string]any, 10)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		var reg map[string]any
+		if err := ws.ReadJSON(&reg); err != nil {
+			return
+		}
+		if err := ws.WriteJSON(map[string]any{"type": hub.MsgTypeRegistered}); err != nil {
+			return
+		}
+		for {
+			var m map[string]any
+			if err := ws.ReadJSON(&m); err != nil {
+				return
+			}
+			ch <- m
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, ch
+}
+
+func TestFailInterrupted_AfterReconnect(t *testing.T) {
+	release := make(chan struct{})
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			<-release
+			return &backend.Response{Text: "late"}, nil
+		},
+	})
+	url, msgs := fakeGateway(t)
+	p.hubCfg.HubURL = url
+	p.hubCfg.ProviderID = "p1"
+	old, err := hub.Connect(p.hubCfg)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	p.hub = old
+
+	p.dispatch(hub.RequestMsg{RequestID: "r1"})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.inflightIDs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// What Start does when the connection drops.
+	interrupted := p.inflightIDs()
+	old.Close()
+	if !p.reconnectLoop() {
+		t.Fatal("reconnectLoop failed")
+	}
+	defer p.hub.Close()
+	p.failInterrupted(interrupted)
+
+	select {
+	case m := <-msgs:
+		if m["type"] != hub.MsgTypeError || m["request_id"] != "r1" {
+			t.Errorf("message = %v, want an error for r1", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("interrupted request was not failed")
+	}
+
+	// The handler finishes later and replies on the dropped connection.
+	close(release)
+	if !p.waitHandlers(5 * time.Second) {
+		t.Fatal("handler did not finish")
+	}
+	if err := old.SendResponse("r1", "late", "p1", 0, hub.Usage{}, nil); !errors.Is(err, hub.ErrClosed) {
+		t.Errorf("late reply on old connection = %v, want hub.ErrClosed", err)
+	}
+	select {
+	case m := <-msgs:
+		t.Errorf("late reply delivered after the request was failed: %v", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDispatch_RefusesAfterStop(t *testing.T) {
+	var called bool
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			called = true
+			return &backend.Response{Text: "late"}, nil
+		},
+	})
+
+	p.Stop()
+	p.dispatch(hub.RequestMsg{RequestID: "r1"})
+	if !p.waitHandlers(time.Second) {
+		t.Fatal("handler started after Stop")
+	}
+	if called || len(p.inflightIDs()) != 0 {
+		t.Error("request dispatched after Stop was handled")
+	}
+}
+
+func TestResolveBackendModel(t *testing.T) {
+	tests := []struct {
+		name   string
+		models []string
+		model  string
+		want   string
+	}{
+		{"exact match", []string{"llama3", "llama3:8b"}, "llama3", ""},
+		{"latest tag", []string{"llama3:8b", "llama3:latest"}, "llama3", "llama3:latest"},
+		{"single tag", []string{"llama3:8b-instruct-q4", "mistral:7b"}, "llama3", "llama3:8b-instruct-q4"},
+		{"ambiguous", []string{"llama3:8b", "llama3:70b"}, "llama3", ""},
+		{"not listed", []string{"mistral:7b"}, "llama3", ""},
+		{"no models", nil, "llama3", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveBackendModel(context.Background(), &stubBackend{models: tt.models}, tt.model)
+			if got != tt.want {
+				t.Errorf("resolveBackendModel(%v, %q) = %q, want %q", tt.models, tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+// --- pause ---
+
+func TestSetPaused_RefusesNewRequests(t *testing.T) {
+	called := false
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			called = true
+			return &backend.Response{}, nil
+		},
+	})
+
+	if !p.SetPaused(true) {
+		t.Fatal("SetPaused(true) reported no change")
+	}
+	if p.SetPaused(true) {
+		t.Error("pausing twice reported a change")
+	}
+	if got := p.Status().Status; got != hub.StatusPaused {
+		t.Errorf("status = %q, want %q", got, hub.StatusPaused)
+	}
+
+	p.dispatch(hub.RequestMsg{RequestID: "r1"})
+	if !p.waitHandlers(5 * time.Second) {
+		t.Fatal("handler did not finish")
+	}
+	if called {
+		t.Error("backend was called while paused")
+	}
+
+	p.SetPaused(false)
+	if got := p.Status().Status; got != hub.StatusOnline {
+		t.Errorf("status after resume = %q, want %q", got, hub.StatusOnline)
+	}
+}
+
+func TestAdoptProviderID(t *testing.T) {
+	p := newTestProvider(1, 5)
+	p.id = "local123"
+	p.hubCfg.ProviderID = "local123"
+
+	p.adoptProviderID("")
+	if p.id != "local123" {
+		t.Errorf("empty ID replaced provider ID with %q", p.id)
+	}
+
+	p.adoptProviderID("gw-42")
+	if p.id != "gw-42" || p.hubCfg.ProviderID != "gw-42" {
+		t.Errorf("id = %q, hubCfg.ProviderID = %q, want gw-42 for both", p.id, p.hubCfg.ProviderID)
+	}
+}
+
+// --- request timeout ---
+
+func TestHandleRequest_TimesOutHungBackend(t *testing.T) {
+	var backendErr error
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			<-ctx.Done()
+			backendErr = ctx.Err()
+			return nil, ctx.Err()
+		},
+	})
+	p.requestTimeout = 20 * time.Millisecond
+
+	p.dispatch(hub.RequestMsg{RequestID: "r1"})
+	if !p.waitHandlers(5 * time.Second) {
+		t.Fatal("hung backend call was not cut off by the request timeout")
+	}
+	if backendErr != context.DeadlineExceeded {
+		t.Errorf("backend context error = %v, want %v", backendErr, context.DeadlineExceeded)
+	}
+
+	// The slot must be free for the next request.
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		t.Error("semaphore slot held after timeout")
+	}
+}
+
+func TestHandleRequest_RejectsWhenQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var served []string
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			mu.Lock()
+			served = append(served, req.Prompt)
+			mu.Unlock()
+			<-release
+			return &backend.Response{Text: "ok"}, nil
+		},
+	})
+	p.maxQueue = 1
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatal(what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	p.dispatch(hub.RequestMsg{RequestID: "r1", Prompt: "first"}) // takes the only slot
+	waitFor("first request never reached the backend", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(served) == 1
+	})
+	p.dispatch(hub.RequestMsg{RequestID: "r2", Prompt: "second"}) // waits for it
+	waitFor("second request never started waiting for a slot", func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.waitq) == 1
+	})
+
+	p.handleRequest(hub.RequestMsg{RequestID: "r3", Prompt: "third"}) // queue full: rejected
+	close(release)
+	if !p.waitHandlers(5 * time.Second) {
+		t.Fatal("handlers did not finish")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(served) != 2 || served[0] != "first" || served[1] != "second" {
+		t.Errorf("served = %v, want [first second]", served)
+	}
+}
+
+func TestAcquireSlot_ServesHigherPriorityFirst(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var served []string
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			mu.Lock()
+			served = append(served, req.Prompt)
+			mu.Unlock()
+			<-release
+			return &backend.Response{Text: "ok"}, nil
+		},
+	})
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatal(what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	p.dispatch(hub.RequestMsg{RequestID: "r0", Prompt: "first"}) // takes the only slot
+	waitFor("first request never reached the backend", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(served) == 1
+	})
+	for i, prio := range []string{hub.PriorityLow, "", hub.PriorityHigh, hub.PriorityLow} {
+		p.dispatch(hub.RequestMsg{
+			RequestID: fmt.Sprintf("r%d", i+1),
+			Prompt:    fmt.Sprintf("%d-%s", i+1, prio),
+			Params:    hub.InferenceParams{Priority: prio},
+		})
+		waitFor("request never queued", func() bool {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return len(p.waitq) == i+1
+		})
+	}
+
+	close(release)
+	if !p.waitHandlers(5 * time.Second) {
+		t.Fatal("handlers did not finish")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"first", "3-high", "2-", "1-low", "4-low"}
+	if strings.Join(served, " ") != strings.Join(want, " ") {
+		t.Errorf("served = %v, want %v", served, want)
+	}
+}
+
+// --- startup health retries ---
+
+// flakyBackend fails its first failures health checks.
+type flakyBackend struct {
+	stubBackend
+	failures int
+	calls    int
+}
+
+func (f *flakyBackend) Health(ctx context.Context) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestStart_ReturnsErrBackendDown(t *testing.T) {
+	p := newHandlerTestProvider(&flakyBackend{failures: maxHealthCheckAttempts})
+	p.recoveryInterval = time.Millisecond
+	url, msgs := fakeGateway(t)
+	p.hubCfg.HubURL = url
+	h, err := hub.Connect(p.hubCfg)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	p.hub = h
+
+	errc := make(chan error, 1)
+	go func() { errc <- p.Start(context.Background()) }()
+	<-msgs // initial heartbeat: Start is reading
+	go p.onModelServerDown()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrBackendDown) {
+			t.Fatalf("Start = %v, want ErrBackendDown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after the backend stayed down")
+	}
+
+	unregisters := 0
+	for {
+		select {
+		case m := <-msgs:
+			if m["type"] == hub.MsgTypeUnregister {
+				unregisters++
+			}
+			continue
+		case <-time.After(100 * time.Millisecond):
+		}
+		break
+	}
+	if unregisters != 1 {
+		t.Errorf("unregister messages = %d, want 1", unregisters)
+	}
+}
+
+func TestWaitHealthy_RetriesUntilHealthy(t *testing.T) {
+	b := &flakyBackend{failures: 2}
+	cfg := Config{HealthRetries: 3, HealthRetryInterval: time.Millisecond}
+	if err := waitHealthy(b, cfg); err != nil {
+		t.Fatalf("waitHealthy: %v", err)
+	}
+	if b.calls != 3 {
+		t.Errorf("health checks = %d, want 3", b.calls)
+	}
+}
+
+func TestWaitHealthy_GivesUpAfterRetries(t *testing.T) {
+	b := &flakyBackend{failures: 10}
+	cfg := Config{HealthRetries: 2, HealthRetryInterval: time.Millisecond}
+	if err := waitHealthy(b, cfg); err == nil {
+		t.Fatal("expected an error from a backend that never becomes healthy")
+	}
+	if b.calls != 3 {
+		t.Errorf("health checks = %d, want 3 (1 + 2 retries)", b.calls)
+	}
+}
+
+func TestHealthRetryDelay(t *testing.T) {
+	cases := []struct {
+		interval time.Duration
+		attempt  int
+		want     time.Duration
+	}{
+		{0, 0, defaultHealthRetryInterval},
+		{time.Second, 0, time.Second},
+		{time.Second, 3, 8 * time.Second},
+		{time.Second, 10, maxHealthRetryInterval},
+		{time.Minute, 0, maxHealthRetryInterval},
+	}
+	for _, c := range cases {
+		if got := healthRetryDelay(c.interval, c.attempt); got != c.want {
+			t.Errorf("healthRetryDelay(%s, %d) = %s, want %s", c.interval, c.attempt, got, c.want)
+		}
+	}
+}
+
+// --- events ---
+
+// recordingEvents records the request and error events it receives.
+type recordingEvents struct {
+	NopEventHandler
+	mu       sync.Mutex
+	requests []string
+	errors   []string
+}
+
+func (r *recordingEvents) OnRequest(requestID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, requestID)
+}
+
+func (r *recordingEvents) OnError(requestID string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, requestID)
+}
+
+func TestEvents_RequestAndError(t *testing.T) {
+	p := newHandlerTestProvider(&stubBackend{
+		complete: func(ctx context.Context, req *backend.Request) (*backend.Response, error) {
+			if req.Prompt == "fail" {
+				return nil, errors.New("boom")
+			}
+			return &backend.Response{Text: "ok"}, nil
+		},
+	})
+	events := &recordingEvents{}
+	p.eventHandler = events
+
+	p.dispatch(hub.RequestMsg{RequestID: "ok", Prompt: "hi"})
+	p.dispatch(hub.RequestMsg{RequestID: "bad", Prompt: "fail"})
+	if !p.waitHandlers(5 * time.Second) {
+		t.Fatal("handlers did not finish")
+	}
+
+	if len(events.requests) != 2 {
+		t.Errorf("OnRequest calls = %v, want 2", events.requests)
+	}
+	if len(events.errors) != 1 || events.errors[0] != "bad" {
+		t.Errorf("OnError calls = %v, want [bad]", events.errors)
+	}
+}
+
+func TestEvents_NilHandlerIsNoop(t *testing.T) {
+	p := newTestProvider(1, 5)
+	p.events().OnConnected("p1") // must not panic
+}
+
+// --- heartbeats ---
+
+func TestHeartbeatRetryDelay(t *testing.T) {
+	cases := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, heartbeatRetryBase},
+		{2, 2 * heartbeatRetryBase},
+		{3, 4 * heartbeatRetryBase},
+		{10, heartbeatRetryMax},
+	}
+	for _, c := range cases {
+		for i := 0; i < 20; i++ {
+			got := heartbeatRetryDelay(c.attempt)
+			if got < c.base || got > c.base+c.base/2 {
+				t.Fatalf("heartbeatRetryDelay(%d) = %s, want within [%s, %s]", c.attempt, got, c.base, c.base+c.base/2)
+			}
+		}
+	}
+}
+
+func TestSendHeartbeat_ClosedConnectionIsNotRetried(t *testing.T) {
+	p := newHandlerTestProvider(&stubBackend{})
+	p.sendHeartbeat()
+
+	p.mu.Lock()
+	retrying := p.heartbeatRetrying
+	p.mu.Unlock()
+	if retrying {
+		t.Error("heartbeat on a closed connection should not be retried")
+	}
+}
+
+func TestStatus_ReportsHeartbeatFailures(t *testing.T) {
+	p := newTestProvider(1, 5)
+	p.mu.Lock()
+	p.heartbeatFailures = 2
+	p.mu.Unlock()
+
+	if got := p.Status().HeartbeatFailures; got != 2 {
+		t.Errorf("HeartbeatFailures = %d, want 2", got)
+	}
+}
+
+// --- provider identity ---
+
+func TestValidateProviderID(t *testing.T) {
+	for _, id := range []string{"a1b2c3d4", "gpu-box_1.prod", strings.Repeat("x", 64)} {
+		if err := ValidateProviderID(id); err != nil {
+			t.Errorf("ValidateProviderID(%q) = %v, want nil", id, err)
+		}
+	}
+	for _, id := range []string{"", "has space", "slash/id", strings.Repeat("x", 65)} {
+		if err := ValidateProviderID(id); err == nil {
+			t.Errorf("ValidateProviderID(%q) = nil, want error", id)
+		}
+	}
+}
+
+func TestStableProviderID(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	first, err := StableProviderID("llama3")
+	if err != nil {
+		t.Fatalf("StableProviderID: %v", err)
+	}
+	again, err := StableProviderID("llama3")
+	if err != nil {
+		t.Fatalf("StableProviderID: %v", err)
+	}
+	if first != again {
+		t.Errorf("ID changed across calls: %q then %q", first, again)
+	}
+	if err := ValidateProviderID(first); err != nil {
+		t.Errorf("derived ID is invalid: %v", err)
+	}
+
+	other, err := StableProviderID("mistral")
+	if err != nil {
+		t.Fatalf("StableProviderID: %v", err)
+	}
+	if other == first {
+		t.Errorf("models share provider ID %q", first)
+	}
+}
+
+func TestStreamCoalescer(t *testing.T) {
+	var mu sync.Mutex
+	var chunks []string
+	c := newStreamCoalescer(time.Hour, func(chunk string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		chunks = append(chunks, chunk)
+		return nil
+	})
+
+	for _, tok := range []string{"Hel", "lo", ","} {
+		if err := c.add(tok); err != nil {
+			t.Fatalf("add(%q) error = %v", tok, err)
+		}
+	}
+	if len(chunks) != 0 {
+		t.Fatalf("sent %q before the window elapsed", chunks)
+	}
+	if err := c.flush(); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+	c.add(" world")
+	c.flush()
+	c.flush() // nothing buffered: no empty chunk
+
+	want := []string{"Hello,", " world"}
+	if !slices.Equal(chunks, want) {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+}
+
+func TestStreamCoalescer_TimedFlush(t *testing.T) {
+	sent := make(chan string, 1)
+	errSend := errors.New("connection closed")
+	c := newStreamCoalescer(10*time.Millisecond, func(chunk string) error {
+		sent <- chunk
+		return errSend
+	})
+
+	c.add("a")
+	c.add("b")
+	select {
+	case got := <-sent:
+		if got != "ab" {
+			t.Errorf("chunk = %q, want %q", got, "ab")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("buffered tokens were not sent after the window")
+	}
+
+	if err := c.add("c"); !errors.Is(err, errSend) {
+		t.Errorf("add() after a failed send: error = %v, want %v", err, errSend)
+	}
+	if err := c.flush(); !errors.Is(err, errSend) {
+		t.Errorf("flush() error = %v, want %v", err, errSend)
+	}
+}
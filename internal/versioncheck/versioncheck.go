@@ -102,6 +102,12 @@ func (c *Checker) setResult(latest string) {
 	c.mu.Unlock()
 }
 
+// IsNewer reports whether latest is strictly newer than current.
+// Both versions may carry a leading "v" (e.g. "v0.6.1").
+func IsNewer(latest, current string) bool {
+	return isNewer(normalizeVersion(latest), normalizeVersion(current))
+}
+
 // isNewer returns true if version a is strictly newer than version b.
 // Compares semver-style dot-separated integers (e.g. "0.5.3" > "0.5.2").
 func isNewer(a, b string) bool {